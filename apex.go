@@ -1,6 +1,7 @@
 package soapforce
 
 import (
+	"context"
 	"encoding/xml"
 	"time"
 )
@@ -477,8 +478,12 @@ type WsdlToApexResult struct {
 
 /* Compile one or more Apex Classes, Triggers, and run tests. */
 func (s *Soap) CompileAndTest(request *CompileAndTest) (*CompileAndTestResponse, error) {
+	return s.CompileAndTestContext(context.Background(), request)
+}
+
+func (s *Soap) CompileAndTestContext(ctx context.Context, request *CompileAndTest) (*CompileAndTestResponse, error) {
 	response := new(CompileAndTestResponse)
-	err := s.client.Call(request, response, s.responseHeader)
+	err := s.client.CallContext(ctx, request, response, s.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -488,8 +493,12 @@ func (s *Soap) CompileAndTest(request *CompileAndTest) (*CompileAndTestResponse,
 
 /* Compile one or more Apex Classes. */
 func (s *Soap) CompileClasses(request *CompileClasses) (*CompileClassesResponse, error) {
+	return s.CompileClassesContext(context.Background(), request)
+}
+
+func (s *Soap) CompileClassesContext(ctx context.Context, request *CompileClasses) (*CompileClassesResponse, error) {
 	response := new(CompileClassesResponse)
-	err := s.client.Call(request, response, s.responseHeader)
+	err := s.client.CallContext(ctx, request, response, s.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -499,8 +508,12 @@ func (s *Soap) CompileClasses(request *CompileClasses) (*CompileClassesResponse,
 
 /* Compile Apex Trigger code blocks. */
 func (s *Soap) CompileTriggers(request *CompileTriggers) (*CompileTriggersResponse, error) {
+	return s.CompileTriggersContext(context.Background(), request)
+}
+
+func (s *Soap) CompileTriggersContext(ctx context.Context, request *CompileTriggers) (*CompileTriggersResponse, error) {
 	response := new(CompileTriggersResponse)
-	err := s.client.Call(request, response, s.responseHeader)
+	err := s.client.CallContext(ctx, request, response, s.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -510,8 +523,12 @@ func (s *Soap) CompileTriggers(request *CompileTriggers) (*CompileTriggersRespon
 
 /* Execute an anonymous Apex code block */
 func (s *Soap) ExecuteAnonymous(request *ExecuteAnonymous) (*ExecuteAnonymousResponse, error) {
+	return s.ExecuteAnonymousContext(context.Background(), request)
+}
+
+func (s *Soap) ExecuteAnonymousContext(ctx context.Context, request *ExecuteAnonymous) (*ExecuteAnonymousResponse, error) {
 	response := new(ExecuteAnonymousResponse)
-	err := s.client.Call(request, response, s.responseHeader)
+	err := s.client.CallContext(ctx, request, response, s.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -521,8 +538,12 @@ func (s *Soap) ExecuteAnonymous(request *ExecuteAnonymous) (*ExecuteAnonymousRes
 
 /* Execute test methods */
 func (s *Soap) RunTests(request *RunTests) (*RunTestsResponse, error) {
+	return s.RunTestsContext(context.Background(), request)
+}
+
+func (s *Soap) RunTestsContext(ctx context.Context, request *RunTests) (*RunTestsResponse, error) {
 	response := new(RunTestsResponse)
-	err := s.client.Call(request, response, s.responseHeader)
+	err := s.client.CallContext(ctx, request, response, s.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -532,8 +553,12 @@ func (s *Soap) RunTests(request *RunTests) (*RunTestsResponse, error) {
 
 /* Generate Apex packages from WSDL for web s callouts */
 func (s *Soap) WsdlToApex(request *WsdlToApex) (*WsdlToApexResponse, error) {
+	return s.WsdlToApexContext(context.Background(), request)
+}
+
+func (s *Soap) WsdlToApexContext(ctx context.Context, request *WsdlToApex) (*WsdlToApexResponse, error) {
 	response := new(WsdlToApexResponse)
-	err := s.client.Call(request, response, s.responseHeader)
+	err := s.client.CallContext(ctx, request, response, s.responseHeader)
 	if err != nil {
 		return nil, err
 	}
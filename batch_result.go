@@ -0,0 +1,259 @@
+package soapforce
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BatchResult splits the results of a DML call into the records that
+// succeeded and the ones that failed, each with its position in the input.
+//
+//	res, err := client.Create(records)
+//	batch := soapforce.NewSaveBatchResult(records, res, err)
+//	for _, f := range batch.Failed {
+//		fmt.Println(f.Index, f.StatusCode, f.Fields, f.Message)
+//	}
+type BatchResult struct {
+	Succeeded []*RecordSuccess
+	Failed    []*RecordFailure
+}
+
+type RecordSuccess struct {
+	Index int
+	// Record is nil for Delete, Undelete and EmptyRecycleBin.
+	Record  *SObject
+	Id      string
+	Created bool
+}
+
+// RecordFailure is a record that was not saved. StatusCode, Fields and
+// Message are those of the first of Errors. Err is set instead of Errors
+// when the call for the record failed as a whole, see ChunkErrors.
+type RecordFailure struct {
+	Index int
+	// Record is nil for Delete, Undelete and EmptyRecycleBin.
+	Record     *SObject
+	Id         string
+	StatusCode StatusCode
+	Fields     []string
+	Message    string
+	Errors     []*Error
+	Err        error
+}
+
+func (f *RecordFailure) Error() string {
+	if f.Err != nil {
+		return fmt.Sprintf("record %d: %v", f.Index, f.Err)
+	}
+	msg := fmt.Sprintf("record %d: %s: %s", f.Index, f.StatusCode, f.Message)
+	if len(f.Fields) > 0 {
+		msg += fmt.Sprintf(" (%s)", strings.Join(f.Fields, ", "))
+	}
+	return msg
+}
+
+func (f *RecordFailure) Unwrap() error {
+	return f.Err
+}
+
+// Is makes errors.Is(err, StatusCodeREQUIRED_FIELD_MISSING) and friends work.
+func (f *RecordFailure) Is(target error) bool {
+	code, ok := target.(StatusCode)
+	if !ok {
+		return false
+	}
+	for _, e := range f.Errors {
+		if e.StatusCode != nil && *e.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (c StatusCode) Error() string {
+	return string(c)
+}
+
+// NewSaveBatchResult maps the results of Create or Update to records. err
+// is the error returned with the results; when it is ChunkErrors the
+// records of the failed chunks are failures with Err set.
+func NewSaveBatchResult(records []*SObject, results []*SaveResult, err error) *BatchResult {
+	return newBatchResult(len(records), err, func(i int) (*SObject, string, bool, bool, []*Error, bool) {
+		if i >= len(results) || results[i] == nil {
+			return records[i], "", false, false, nil, false
+		}
+		r := results[i]
+		return records[i], r.Id, r.Success, false, r.Errors, true
+	})
+}
+
+func NewUpsertBatchResult(records []*SObject, results []*UpsertResult, err error) *BatchResult {
+	return newBatchResult(len(records), err, func(i int) (*SObject, string, bool, bool, []*Error, bool) {
+		if i >= len(results) || results[i] == nil {
+			return records[i], "", false, false, nil, false
+		}
+		r := results[i]
+		return records[i], r.Id, r.Success, r.Created, r.Errors, true
+	})
+}
+
+func NewDeleteBatchResult(ids []string, results []*DeleteResult, err error) *BatchResult {
+	return newBatchResult(len(ids), err, func(i int) (*SObject, string, bool, bool, []*Error, bool) {
+		if i >= len(results) || results[i] == nil {
+			return nil, ids[i], false, false, nil, false
+		}
+		return nil, ids[i], results[i].Success, false, results[i].Errors, true
+	})
+}
+
+func NewUndeleteBatchResult(ids []string, results []*UndeleteResult, err error) *BatchResult {
+	return newBatchResult(len(ids), err, func(i int) (*SObject, string, bool, bool, []*Error, bool) {
+		if i >= len(results) || results[i] == nil {
+			return nil, ids[i], false, false, nil, false
+		}
+		return nil, ids[i], results[i].Success, false, results[i].Errors, true
+	})
+}
+
+func NewEmptyRecycleBinBatchResult(ids []string, results []*EmptyRecycleBinResult, err error) *BatchResult {
+	return newBatchResult(len(ids), err, func(i int) (*SObject, string, bool, bool, []*Error, bool) {
+		if i >= len(results) || results[i] == nil {
+			return nil, ids[i], false, false, nil, false
+		}
+		return nil, ids[i], results[i].Success, false, results[i].Errors, true
+	})
+}
+
+// newBatchResult builds a BatchResult from the n results returned by
+// result, which reports false when there is no result for a record.
+func newBatchResult(n int, err error, result func(i int) (record *SObject, id string, success, created bool, errs []*Error, ok bool)) *BatchResult {
+	var chunkErrs ChunkErrors
+	errors.As(err, &chunkErrs)
+	callErr := func(i int) error {
+		for _, e := range chunkErrs {
+			if i >= e.Start && i < e.End {
+				return e.Err
+			}
+		}
+		if err != nil && chunkErrs == nil {
+			return err
+		}
+		return errors.New("soapforce: no result for the record")
+	}
+
+	b := &BatchResult{}
+	for i := 0; i < n; i++ {
+		record, id, success, created, errs, ok := result(i)
+		switch {
+		case !ok:
+			b.Failed = append(b.Failed, &RecordFailure{Index: i, Record: record, Id: id, Err: callErr(i)})
+		case success:
+			b.Succeeded = append(b.Succeeded, &RecordSuccess{Index: i, Record: record, Id: id, Created: created})
+		default:
+			f := &RecordFailure{Index: i, Record: record, Id: id, Errors: errs}
+			if len(errs) > 0 {
+				if errs[0].StatusCode != nil {
+					f.StatusCode = *errs[0].StatusCode
+				}
+				f.Fields = errs[0].Fields
+				f.Message = errs[0].Message
+			}
+			b.Failed = append(b.Failed, f)
+		}
+	}
+	return b
+}
+
+// Err returns a *BatchError if any record failed.
+func (b *BatchResult) Err() error {
+	if len(b.Failed) == 0 {
+		return nil
+	}
+	return &BatchError{Failures: b.Failed, Total: len(b.Failed) + len(b.Succeeded)}
+}
+
+// Retryable returns the failures worth sending again under p, or
+// DefaultRetryPolicy if p is nil: the records that failed with one of
+// p.RetryableStatusCodes, along with the ones rolled back by an all-or-none
+// call, and the records of calls that failed with one of p.RetryableCodes
+// or a network error.
+func (b *BatchResult) Retryable(p *RetryPolicy) []*RecordFailure {
+	if p == nil {
+		p = DefaultRetryPolicy()
+	}
+	var retry []*RecordFailure
+	var errs [][]*Error
+	var recordFailures []*RecordFailure
+	for _, f := range b.Failed {
+		if f.Err != nil {
+			if p.retryableError(f.Err) {
+				retry = append(retry, f)
+			}
+			continue
+		}
+		errs = append(errs, f.Errors)
+		recordFailures = append(recordFailures, f)
+	}
+	for _, i := range p.retryIndexes(errs) {
+		retry = append(retry, recordFailures[i])
+	}
+	sort.Slice(retry, func(i, j int) bool { return retry[i].Index < retry[j].Index })
+	return retry
+}
+
+// RetryRecords returns the records of Retryable, to send again with the
+// same call. The i-th record was at Retryable(p)[i].Index in the input.
+func (b *BatchResult) RetryRecords(p *RetryPolicy) []*SObject {
+	failures := b.Retryable(p)
+	records := make([]*SObject, len(failures))
+	for i, f := range failures {
+		records[i] = f.Record
+	}
+	return records
+}
+
+// RetryIds is RetryRecords for Delete, Undelete and EmptyRecycleBin.
+func (b *BatchResult) RetryIds(p *RetryPolicy) []string {
+	failures := b.Retryable(p)
+	ids := make([]string, len(failures))
+	for i, f := range failures {
+		ids[i] = f.Id
+	}
+	return ids
+}
+
+// BatchError reports the records of a DML call that failed.
+type BatchError struct {
+	Failures []*RecordFailure
+	Total    int
+}
+
+// maxBatchErrorRecords is the number of failures listed in the message of a
+// BatchError.
+const maxBatchErrorRecords = 5
+
+func (e *BatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "soapforce: %d of %d records failed", len(e.Failures), e.Total)
+	for i, f := range e.Failures {
+		if i == maxBatchErrorRecords {
+			fmt.Fprintf(&b, "; and %d more", len(e.Failures)-i)
+			break
+		}
+		b.WriteString("; ")
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+// Is reports whether any record failed with target, e.g. a StatusCode.
+func (e *BatchError) Is(target error) bool {
+	for _, f := range e.Failures {
+		if errors.Is(f, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package soapforce
+
+import (
+	"context"
+	"reflect"
+)
+
+// CallOption customizes a single call. The SOAP headers it sets are merged
+// with the client defaults and replace a default header of the same type.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	headers            []interface{}
+	retryNonIdempotent bool
+	typedFields        bool
+	readAhead          bool
+	fetchChildren      bool
+	chunkSize          int
+	concurrency        int
+}
+
+type callOptionsKey struct{}
+
+// WithCallOptions stores call options in ctx, where SOAPClient picks them
+// up. Use it to pass options to the Soap methods. Options already on ctx are
+// kept and applied first.
+func WithCallOptions(ctx context.Context, opts ...CallOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+	o := &callOptions{}
+	if parent, ok := ctx.Value(callOptionsKey{}).(*callOptions); ok && parent != nil {
+		o.headers = append(o.headers, parent.headers...)
+		o.retryNonIdempotent = parent.retryNonIdempotent
+		o.typedFields = parent.typedFields
+		o.readAhead = parent.readAhead
+		o.fetchChildren = parent.fetchChildren
+		o.chunkSize = parent.chunkSize
+		o.concurrency = parent.concurrency
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return context.WithValue(ctx, callOptionsKey{}, o)
+}
+
+func callOptionsFrom(ctx context.Context) *callOptions {
+	if o, ok := ctx.Value(callOptionsKey{}).(*callOptions); ok && o != nil {
+		return o
+	}
+	return &callOptions{}
+}
+
+// mergeHeaders returns defaults with every header replaced by the one of the
+// same type in overrides. Headers only found in overrides are appended.
+func mergeHeaders(defaults, overrides []interface{}) []interface{} {
+	if len(overrides) == 0 {
+		return defaults
+	}
+	merged := make([]interface{}, 0, len(defaults)+len(overrides))
+	index := map[reflect.Type]int{}
+	for _, h := range defaults {
+		index[reflect.TypeOf(h)] = len(merged)
+		merged = append(merged, h)
+	}
+	for _, h := range overrides {
+		if i, ok := index[reflect.TypeOf(h)]; ok {
+			merged[i] = h
+			continue
+		}
+		index[reflect.TypeOf(h)] = len(merged)
+		merged = append(merged, h)
+	}
+	return merged
+}
+
+// WithHeader sends h, e.g. a *PackageVersionHeader, with the call.
+func WithHeader(h interface{}) CallOption {
+	return func(o *callOptions) {
+		o.headers = append(o.headers, h)
+	}
+}
+
+// AllOrNone rolls back the whole call if any record fails.
+func AllOrNone(allOrNone bool) CallOption {
+	return WithHeader(&AllOrNoneHeader{AllOrNone: allOrNone})
+}
+
+// AllowFieldTruncation truncates strings that are too long instead of
+// failing.
+func AllowFieldTruncation(allow bool) CallOption {
+	return WithHeader(&AllowFieldTruncationHeader{AllowFieldTruncation: allow})
+}
+
+// DuplicateRule controls how duplicate rules are applied.
+func DuplicateRule(allowSave, includeRecordDetails, runAsCurrentUser bool) CallOption {
+	return WithHeader(&DuplicateRuleHeader{
+		AllowSave:            allowSave,
+		IncludeRecordDetails: includeRecordDetails,
+		RunAsCurrentUser:     runAsCurrentUser,
+	})
+}
+
+// TriggerAssignmentRules runs the assignment rule with the given id.
+func TriggerAssignmentRules(assignmentRuleId string) CallOption {
+	return WithHeader(&AssignmentRuleHeader{AssignmentRuleId: assignmentRuleId})
+}
+
+// UseDefaultAssignmentRule runs the default assignment rule.
+func UseDefaultAssignmentRule() CallOption {
+	return WithHeader(&AssignmentRuleHeader{UseDefaultRule: true})
+}
+
+// TriggerEmails sends the auto-response, other and user emails.
+func TriggerEmails(autoResponse, other, user bool) CallOption {
+	return WithHeader(&EmailHeader{
+		TriggerAutoResponseEmail: autoResponse,
+		TriggerOtherEmail:        other,
+		TriggerUserEmail:         user,
+	})
+}
+
+// UpdateMru updates the most recently used items list.
+func UpdateMru(update bool) CallOption {
+	return WithHeader(&MruHeader{UpdateMru: update})
+}
+
+// DisableFeedTracking turns feed tracking off for the call.
+func DisableFeedTracking(disable bool) CallOption {
+	return WithHeader(&DisableFeedTrackingHeader{DisableFeedTracking: disable})
+}
+
+// OwnerChange sets what happens to related records when the owner changes.
+func OwnerChange(options ...*OwnerChangeOption) CallOption {
+	return WithHeader(&OwnerChangeOptions{Options: options})
+}
+
+// UserTerritoryDelete transfers the records of deleted user territories to
+// the given user.
+func UserTerritoryDelete(transferToUserId string) CallOption {
+	return WithHeader(&UserTerritoryDeleteHeader{TransferToUserId: transferToUserId})
+}
+
+// BatchSize sets the number of records returned per query batch.
+func BatchSize(size int) CallOption {
+	return WithHeader(&QueryOptions{BatchSize: int32(size)})
+}
+
+// Debugging requests a debug log for the given categories.
+func Debugging(categories []*LogInfo) CallOption {
+	return WithHeader(&DebuggingHeader{Categories: categories})
+}
+
+// RetryNonIdempotent allows the retry policy to retry the call even though
+// it is not idempotent, e.g. a Create.
+func RetryNonIdempotent() CallOption {
+	return func(o *callOptions) {
+		o.retryNonIdempotent = true
+	}
+}
+
+// TypedFields decodes the SObject fields of the response into Go values
+// matching their xsi:type, see SObject.ConvertFieldTypes.
+func TypedFields() CallOption {
+	return func(o *callOptions) {
+		o.typedFields = true
+	}
+}
+
+// ReadAhead makes a QueryIterator fetch the next batch of records while the
+// current one is being read.
+func ReadAhead() CallOption {
+	return func(o *callOptions) {
+		o.readAhead = true
+	}
+}
+
+// FetchAllChildren makes Query, QueryAll and QueryMore call QueryMore on
+// the subquery results of every record until they are complete. A
+// QueryIterator does it for each record as it is reached.
+func FetchAllChildren() CallOption {
+	return func(o *callOptions) {
+		o.fetchChildren = true
+	}
+}
@@ -0,0 +1,147 @@
+package soapforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MaxChunkSize is the number of records a DML call accepts.
+const MaxChunkSize = 200
+
+// ChunkError is the failure of the call for the records in [Start, End) of
+// the input.
+type ChunkError struct {
+	Start int
+	End   int
+	Err   error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("records %d-%d: %v", e.Start, e.End-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkErrors is returned by a DML call split into chunks when some of them
+// failed, sorted by Start. The results of the records of a failed chunk are
+// nil; the other results are set.
+type ChunkErrors []*ChunkError
+
+func (e ChunkErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("soapforce: %d of the chunks failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Is reports whether the error of any chunk matches target.
+func (e ChunkErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of a chunk that matches target.
+func (e ChunkErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChunkSize sets the number of records sent per call by Create, Update,
+// Upsert, Delete, Undelete and EmptyRecycleBin, at most MaxChunkSize, the
+// default. AllOrNone applies to each chunk separately.
+func ChunkSize(n int) CallOption {
+	return func(o *callOptions) {
+		o.chunkSize = n
+	}
+}
+
+// Concurrency sets how many chunks of a DML call are sent at the same time.
+// Chunks are sent one after the other by default.
+func Concurrency(n int) CallOption {
+	return func(o *callOptions) {
+		o.concurrency = n
+	}
+}
+
+// chunked calls call for each chunk of n records, as set by the ChunkSize
+// and Concurrency options of ctx. A single chunk returns the error of the
+// call as is, otherwise the failed chunks are returned as ChunkErrors.
+func chunked(ctx context.Context, n int, call func(ctx context.Context, start, end int) error) error {
+	o := callOptionsFrom(ctx)
+	size := o.chunkSize
+	if size <= 0 || size > MaxChunkSize {
+		size = MaxChunkSize
+	}
+	if n <= size {
+		return call(ctx, 0, n)
+	}
+	concurrency := o.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mu   sync.Mutex
+		errs ChunkErrors
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(start, end int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := ctx.Err()
+			if err == nil {
+				err = call(ctx, start, end)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, &ChunkError{Start: start, End: end, Err: err})
+				mu.Unlock()
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Start < errs[j].Start })
+	return errs
+}
+
+// chunkResults checks that a chunk got one result per record.
+func chunkResults(start, end, got int) error {
+	if got != end-start {
+		return fmt.Errorf("soapforce: got %d results for %d records", got, end-start)
+	}
+	return nil
+}
+
+// partial reports whether err left some results of a chunked call set.
+func partial(err error) bool {
+	_, ok := err.(ChunkErrors)
+	return ok
+}
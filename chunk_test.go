@@ -0,0 +1,154 @@
+package soapforce
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var recordNamePattern = regexp.MustCompile(`<Name>R(\d+)</Name>`)
+
+// newSOAPServer answers every request with the body returned by respond, an
+// empty string failing the call with INVALID_FIELD.
+func newSOAPServer(t *testing.T, respond func(request string) string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, err := requestBody(r)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		body := respond(request)
+		if body == "" {
+			body = invalidFieldFaultXML
+		}
+		writeEnvelope(w, body)
+	}))
+}
+
+func newTestClient(url string) *Client {
+	c := NewClient()
+	c.SetServerUrl(url)
+	c.SetLogger(ioutil.Discard)
+	c.SetAccessToken("sid")
+	return c
+}
+
+func namedRecords(n int) []*SObject {
+	records := make([]*SObject, n)
+	for i := range records {
+		records[i] = &SObject{Type: "Account", Fields: map[string]interface{}{"Name": fmt.Sprintf("R%d", i)}}
+	}
+	return records
+}
+
+// createResponse returns one result per record, with an id made of the
+// number in its name.
+func createResponse(request string) string {
+	var results strings.Builder
+	for _, m := range recordNamePattern.FindAllStringSubmatch(request, -1) {
+		results.WriteString("<result><id>id-" + m[1] + "</id><success>true</success></result>")
+	}
+	return "<createResponse>" + results.String() + "</createResponse>"
+}
+
+func TestCreateChunks(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []CallOption
+		sizes       []int
+		concurrency int
+	}{
+		{"default", nil, []int{50, 200, 200}, 1},
+		{"chunk size", []CallOption{ChunkSize(100)}, []int{50, 100, 100, 100, 100}, 1},
+		{"above the maximum", []CallOption{ChunkSize(500)}, []int{50, 200, 200}, 1},
+		{"concurrent", []CallOption{ChunkSize(100), Concurrency(3)}, []int{50, 100, 100, 100, 100}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var sizes []int
+			inFlight, maxInFlight := 0, 0
+			server := newSOAPServer(t, func(request string) string {
+				mu.Lock()
+				sizes = append(sizes, strings.Count(request, "<sObjects"))
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				return createResponse(request)
+			})
+			defer server.Close()
+
+			results, err := newTestClient(server.URL).Create(namedRecords(450), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Ints(sizes)
+			if fmt.Sprint(sizes) != fmt.Sprint(tt.sizes) {
+				t.Errorf("sent chunks of %v records, want %v", sizes, tt.sizes)
+			}
+			for i, r := range results {
+				if want := fmt.Sprintf("id-%d", i); r == nil || r.Id != want {
+					t.Fatalf("result %d = %+v, want id %s", i, r, want)
+				}
+			}
+			if maxInFlight > tt.concurrency {
+				t.Errorf("%d chunks were sent at the same time, want at most %d", maxInFlight, tt.concurrency)
+			}
+		})
+	}
+}
+
+func TestCreateChunkErrors(t *testing.T) {
+	server := newSOAPServer(t, func(request string) string {
+		if strings.Contains(request, "<Name>R200</Name>") {
+			return ""
+		}
+		return createResponse(request)
+	})
+	defer server.Close()
+
+	results, err := newTestClient(server.URL).Create(namedRecords(450), Concurrency(3))
+	var chunkErrs ChunkErrors
+	if !errors.As(err, &chunkErrs) {
+		t.Fatalf("got %v, want ChunkErrors", err)
+	}
+	if len(chunkErrs) != 1 || chunkErrs[0].Start != 200 || chunkErrs[0].End != 400 {
+		t.Fatalf("got %v, want records 200-399 to fail", err)
+	}
+	if !errors.Is(err, ExceptionCodeINVALID_FIELD) {
+		t.Errorf("errors.Is(%v, INVALID_FIELD) = false", err)
+	}
+	if len(results) != 450 {
+		t.Fatalf("got %d results, want 450", len(results))
+	}
+	for i, r := range results {
+		if failed := i >= 200 && i < 400; failed != (r == nil) {
+			t.Fatalf("result %d = %+v", i, r)
+		}
+	}
+}
+
+func TestCreateSingleChunkError(t *testing.T) {
+	server := newSOAPServer(t, func(string) string { return "" })
+	defer server.Close()
+
+	results, err := newTestClient(server.URL).Create(namedRecords(10))
+	var fault *SOAPFault
+	if results != nil || !errors.As(err, &fault) || partial(err) {
+		t.Errorf("got %v, %v, want the fault of the call", results, err)
+	}
+}
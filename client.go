@@ -1,12 +1,15 @@
 package soapforce
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -14,7 +17,11 @@ const (
 	DefaultLoginUrl   = "login.salesforce.com"
 )
 
+// Client is safe for concurrent use by multiple goroutines. Its exported
+// fields must only be changed through the setters once the client is shared.
 type Client struct {
+	mu sync.RWMutex
+
 	UserInfo        *GetUserInfoResult
 	ApiVersion      string
 	BatchSize       int32
@@ -24,37 +31,198 @@ type Client struct {
 	ClientID        string
 	ClientSecret    string
 	soapClient      *Soap
+
+	// token is the last token of an OAuth login or refresh.
+	token *OAuthToken
+
+	// logInterceptor is the interceptor of the structured logger, called
+	// by logSlot once it has been added to the chain.
+	logInterceptor Interceptor
+	logSlotAdded   bool
+
+	authMu      sync.Mutex
+	credentials CredentialProvider
+
+	tokenStore TokenStore
+	tokenKey   TokenKey
+}
+
+// ClientOption configures a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithHTTPClient sends every request through hc instead of the pooled
+// default client.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.SetHTTPClient(hc)
+	}
+}
+
+// WithTransport replaces the pooled default transport with rt.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.SetTransport(rt)
+	}
+}
+
+// WithConnectTimeout bounds dialing and the TLS handshake.
+func WithConnectTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.SetConnectTimeout(d)
+	}
+}
+
+// WithReadTimeout bounds the wait for response headers once the request
+// has been written.
+func WithReadTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.SetReadTimeout(d)
+	}
+}
+
+// WithTimeout bounds the whole request, including reading the response body.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.SetTimeout(d)
+	}
+}
+
+// WithCredentialProvider enables automatic re-authentication, see
+// SetCredentialProvider.
+func WithCredentialProvider(p CredentialProvider) ClientOption {
+	return func(c *Client) {
+		c.SetCredentialProvider(p)
+	}
+}
+
+// WithRetryPolicy enables retries of transient failures, see RetryPolicy.
+func WithRetryPolicy(p *RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.SetRetryPolicy(p)
+	}
+}
+
+// WithInterceptors wraps every SOAP round trip with interceptors, see
+// Interceptor.
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(c *Client) {
+		c.AddInterceptor(interceptors...)
+	}
+}
+
+// WithStructuredLogger logs every SOAP round trip through l, see Logger.
+func WithStructuredLogger(l *Logger) ClientOption {
+	return func(c *Client) {
+		c.SetStructuredLogger(l)
+	}
 }
 
-func NewClient() *Client {
+// WithTypedFields decodes SObject fields into typed Go values instead of
+// strings, see SObject.ConvertFieldTypes.
+func WithTypedFields() ClientOption {
+	return func(c *Client) {
+		c.SetTypedFields(true)
+	}
+}
+
+func NewClient(opts ...ClientOption) *Client {
 	soap := NewSoap("", true, nil)
-	return &Client{
+	c := &Client{
 		soapClient: soap,
 		ApiVersion: DefaultApiVersion,
 		LoginUrl:   DefaultLoginUrl,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *Client) SetApiVersion(v string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.ApiVersion = v
 	c.setLoginUrl()
 }
 
 func (c *Client) SetAccessToken(sid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SessionId = sid
+	c.setHeaders()
+}
+
+// Token returns the token of the last OAuth login, refresh or restored
+// session, or nil.
+func (c *Client) Token() *OAuthToken {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// setToken switches the client to the session of token.
+func (c *Client) setToken(token *OAuthToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.soapClient.SetServerUrl(sessionUrl(token.InstanceUrl, c.ApiVersion))
+	c.SessionId = token.AccessToken
+	c.setHeaders()
+	c.token = token
+}
+
+// setSession switches the client to a new session on serverUrl.
+func (c *Client) setSession(serverUrl, sid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.soapClient.SetServerUrl(serverUrl)
 	c.SessionId = sid
 	c.setHeaders()
+	c.token = nil
 }
 
 func (c *Client) SetLoginUrl(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.LoginUrl = url
 	c.setLoginUrl()
 }
 
+// setLoginUrl must be called with c.mu held.
 func (c *Client) setLoginUrl() {
-	url := fmt.Sprintf("https://%s/services/Soap/u/%s", c.LoginUrl, c.ApiVersion)
+	url := fmt.Sprintf("%s/services/Soap/u/%s", loginBaseUrl(c.LoginUrl), c.ApiVersion)
 	c.soapClient.SetServerUrl(url)
 }
 
+// loginBaseUrl returns the URL of a login host. A login URL with a scheme,
+// e.g. http://localhost:8080 for a test server, is used as is.
+func loginBaseUrl(loginUrl string) string {
+	if strings.Contains(loginUrl, "://") {
+		return strings.TrimSuffix(loginUrl, "/")
+	}
+	return "https://" + loginUrl
+}
+
+type clientSettings struct {
+	ApiVersion   string
+	SessionId    string
+	LoginUrl     string
+	ClientID     string
+	ClientSecret string
+}
+
+// settings returns a consistent copy of the settings used to log in.
+func (c *Client) settings() clientSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return clientSettings{
+		ApiVersion:   c.ApiVersion,
+		SessionId:    c.SessionId,
+		LoginUrl:     c.LoginUrl,
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+	}
+}
+
 func (c *Client) SetServerUrl(url string) {
 	c.soapClient.SetServerUrl(url)
 }
@@ -67,202 +235,399 @@ func (c *Client) SetLogger(logger io.Writer) {
 	c.soapClient.SetLogger(logger)
 }
 
+// SetStructuredLogger logs every SOAP round trip through l instead of the
+// previous logger. nil turns structured logging off.
+func (c *Client) SetStructuredLogger(l *Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logInterceptor = nil
+	if l != nil {
+		c.logInterceptor = l.Interceptor()
+	}
+	if !c.logSlotAdded {
+		c.logSlotAdded = true
+		c.soapClient.AddInterceptor(c.logSlot)
+	}
+}
+
+func (c *Client) logSlot(ctx context.Context, info *CallInfo, next Invoker) error {
+	c.mu.RLock()
+	interceptor := c.logInterceptor
+	c.mu.RUnlock()
+	if interceptor == nil {
+		return next(ctx, info)
+	}
+	return interceptor(ctx, info, next)
+}
+
 func (c *Client) SetGzip(gz bool) {
 	c.soapClient.SetGzip(gz)
 }
 
-func (c *Client) Login(u string, p string) (*LoginResult, error) {
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.soapClient.SetHTTPClient(hc)
+}
+
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.soapClient.SetTransport(rt)
+}
+
+func (c *Client) SetConnectTimeout(d time.Duration) {
+	c.soapClient.SetConnectTimeout(d)
+}
+
+func (c *Client) SetReadTimeout(d time.Duration) {
+	c.soapClient.SetReadTimeout(d)
+}
+
+func (c *Client) SetTimeout(d time.Duration) {
+	c.soapClient.SetTimeout(d)
+}
+
+func (c *Client) SetRetryPolicy(p *RetryPolicy) {
+	c.soapClient.SetRetryPolicy(p)
+}
+
+func (c *Client) SetTypedFields(typed bool) {
+	c.soapClient.SetTypedFields(typed)
+}
+
+func (c *Client) AddInterceptor(interceptors ...Interceptor) {
+	c.soapClient.AddInterceptor(interceptors...)
+}
+
+func (c *Client) Login(u string, p string, opts ...CallOption) (*LoginResult, error) {
+	return c.LoginContext(context.Background(), u, p, opts...)
+}
+
+func (c *Client) LoginContext(ctx context.Context, u string, p string, opts ...CallOption) (*LoginResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &Login{
 		Username: u,
 		Password: p,
 	}
-	res, err := c.soapClient.Login(req)
+	res, err := c.soapClient.LoginContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	c.soapClient.SetServerUrl(res.Result.ServerUrl)
+	c.mu.Lock()
 	c.UserInfo = res.Result.UserInfo
-	c.SetAccessToken(res.Result.SessionId)
+	c.mu.Unlock()
+	c.setSession(res.Result.ServerUrl, res.Result.SessionId)
 	return res.Result, nil
 }
 
 func (c *Client) SetClientId(ClientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.ClientID = ClientID
 }
 
 func (c *Client) SetClientSecret(ClientSecret string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.ClientSecret = ClientSecret
 }
 
-func (c *Client) LoginWithOAuth(username, password string) error {
+// LoginWithOAuth logs in with the OAuth username-password flow, using the
+// ClientID and ClientSecret of the client.
+func (c *Client) LoginWithOAuth(username, password string) (*OAuthToken, error) {
+	return c.LoginWithOAuthContext(context.Background(), username, password)
+}
+
+func (c *Client) LoginWithOAuthContext(ctx context.Context, username, password string) (*OAuthToken, error) {
+	settings := c.settings()
 	params := url.Values{}
 	params.Add("grant_type", "password")
-	params.Add("client_id", c.ClientID)
-	params.Add("client_secret", c.ClientSecret)
+	params.Add("client_id", settings.ClientID)
+	params.Add("client_secret", settings.ClientSecret)
 	params.Add("username", username)
 	params.Add("password", password)
-	resp, err := http.PostForm(fmt.Sprintf("https://%s/services/oauth2/token", c.LoginUrl), params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	tokenResponse := map[string]string{}
-	err = json.Unmarshal(b, &tokenResponse)
-	if err != nil {
-		return err
-	}
+	return c.requestToken(ctx, params)
+}
 
-	c.soapClient.SetServerUrl(fmt.Sprintf("%s/services/Soap/u/%s", tokenResponse["instance_url"], c.ApiVersion))
-	c.SetAccessToken(tokenResponse["access_token"])
-	return nil
+// Refresh gets a new access token with a refresh token. The RefreshToken of
+// the result is the one to use next time: a new one if the connected app
+// rotates refresh tokens, refreshToken otherwise. If refreshToken is
+// rejected with invalid_grant, the token saved with it in the token store is
+// deleted.
+func (c *Client) Refresh(refreshToken string) (*OAuthToken, error) {
+	return c.RefreshContext(context.Background(), refreshToken)
 }
 
-func (c *Client) Refresh(refreshToken string) error {
+func (c *Client) RefreshContext(ctx context.Context, refreshToken string) (*OAuthToken, error) {
+	settings := c.settings()
 	params := url.Values{}
 	params.Add("grant_type", "refresh_token")
-	params.Add("client_id", c.ClientID)
-	params.Add("client_secret", c.ClientSecret)
+	params.Add("client_id", settings.ClientID)
+	params.Add("client_secret", settings.ClientSecret)
 	params.Add("refresh_token", refreshToken)
-	resp, err := http.PostForm(fmt.Sprintf("https://%s/services/oauth2/token", c.LoginUrl), params)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	tokenResponse := map[string]string{}
-	err = json.Unmarshal(b, &tokenResponse)
-	if err != nil {
-		return err
+	token, err := c.requestToken(ctx, params)
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant" {
+		// the refresh token was revoked or has expired
+		if derr := c.deleteStoredRefreshToken(ctx, refreshToken); derr != nil {
+			return nil, fmt.Errorf("%v; %v", err, derr)
+		}
 	}
+	return token, err
+}
 
-	c.soapClient.SetServerUrl(fmt.Sprintf("%s/services/Soap/u/%s", tokenResponse["instance_url"], c.ApiVersion))
-	c.SetAccessToken(tokenResponse["access_token"])
-	return nil
+// Logout ends the session and deletes the token saved in the token store,
+// if any.
+func (c *Client) Logout(opts ...CallOption) error {
+	return c.LogoutContext(context.Background(), opts...)
 }
 
-func (c *Client) Logout() error {
-	_, err := c.soapClient.Logout(&Logout{})
+func (c *Client) LogoutContext(ctx context.Context, opts ...CallOption) error {
+	ctx = WithCallOptions(ctx, opts...)
+	_, err := c.soapClient.LogoutContext(ctx, &Logout{})
 	if err != nil {
 		return err
 	}
+	c.mu.Lock()
 	c.setLoginUrl()
 	c.soapClient.ClearHeader()
-	return nil
+	c.token = nil
+	c.mu.Unlock()
+	return c.deleteToken(ctx)
 }
 
-func (c *Client) DescribeSObject(s string) (*DescribeSObjectResult, error) {
+func (c *Client) DescribeSObject(s string, opts ...CallOption) (*DescribeSObjectResult, error) {
+	return c.DescribeSObjectContext(context.Background(), s, opts...)
+}
+
+func (c *Client) DescribeSObjectContext(ctx context.Context, s string, opts ...CallOption) (*DescribeSObjectResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &DescribeSObject{
 		SObjectType: s,
 	}
-	res, err := c.soapClient.DescribeSObject(req)
+	res, err := c.soapClient.DescribeSObjectContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) DescribeGlobal() (*DescribeGlobalResult, error) {
-	res, err := c.soapClient.DescribeGlobal(&DescribeGlobal{})
+func (c *Client) DescribeSObjects(s []string, opts ...CallOption) ([]*DescribeSObjectResult, error) {
+	return c.DescribeSObjectsContext(context.Background(), s, opts...)
+}
+
+func (c *Client) DescribeSObjectsContext(ctx context.Context, s []string, opts ...CallOption) ([]*DescribeSObjectResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	req := &DescribeSObjects{
+		SObjectType: s,
+	}
+	res, err := c.soapClient.DescribeSObjectsContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Result, nil
+}
+
+func (c *Client) DescribeGlobal(opts ...CallOption) (*DescribeGlobalResult, error) {
+	return c.DescribeGlobalContext(context.Background(), opts...)
+}
+
+func (c *Client) DescribeGlobalContext(ctx context.Context, opts ...CallOption) (*DescribeGlobalResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	res, err := c.soapClient.DescribeGlobalContext(ctx, &DescribeGlobal{})
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) DescribeLayout(s string, l string, ids []string) (*DescribeLayoutResultResult, error) {
+func (c *Client) DescribeLayout(s string, l string, ids []string, opts ...CallOption) (*DescribeLayoutResultResult, error) {
+	return c.DescribeLayoutContext(context.Background(), s, l, ids, opts...)
+}
+
+func (c *Client) DescribeLayoutContext(ctx context.Context, s string, l string, ids []string, opts ...CallOption) (*DescribeLayoutResultResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &DescribeLayout{
 		SObjectType:   s,
 		LayoutName:    l,
 		RecordTypeIds: ids,
 	}
-	res, err := c.soapClient.DescribeLayout(req)
+	res, err := c.soapClient.DescribeLayoutContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) Create(s []*SObject) ([]*SaveResult, error) {
-	req := &Create{
-		SObjects: s,
-	}
-	res, err := c.soapClient.Create(req)
-	if err != nil {
+func (c *Client) Create(s []*SObject, opts ...CallOption) ([]*SaveResult, error) {
+	return c.CreateContext(context.Background(), s, opts...)
+}
+
+func (c *Client) CreateContext(ctx context.Context, s []*SObject, opts ...CallOption) ([]*SaveResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	results := make([]*SaveResult, len(s))
+	err := chunked(ctx, len(s), func(ctx context.Context, start, end int) error {
+		res, err := c.soapClient.CreateContext(ctx, &Create{SObjects: s[start:end]})
+		if err != nil {
+			return err
+		}
+		if err := chunkResults(start, end, len(res.Result)); err != nil {
+			return err
+		}
+		copy(results[start:end], res.Result)
+		return nil
+	})
+	if err != nil && !partial(err) {
 		return nil, err
 	}
-	return res.Result, nil
+	return results, err
 }
 
-func (c *Client) Update(s []*SObject) ([]*SaveResult, error) {
-	req := &Update{
-		SObjects: s,
-	}
-	res, err := c.soapClient.Update(req)
-	if err != nil {
+func (c *Client) Update(s []*SObject, opts ...CallOption) ([]*SaveResult, error) {
+	return c.UpdateContext(context.Background(), s, opts...)
+}
+
+func (c *Client) UpdateContext(ctx context.Context, s []*SObject, opts ...CallOption) ([]*SaveResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	results := make([]*SaveResult, len(s))
+	err := chunked(ctx, len(s), func(ctx context.Context, start, end int) error {
+		res, err := c.soapClient.UpdateContext(ctx, &Update{SObjects: s[start:end]})
+		if err != nil {
+			return err
+		}
+		if err := chunkResults(start, end, len(res.Result)); err != nil {
+			return err
+		}
+		copy(results[start:end], res.Result)
+		return nil
+	})
+	if err != nil && !partial(err) {
 		return nil, err
 	}
-	return res.Result, nil
+	return results, err
 }
 
-func (c *Client) Upsert(s []*SObject, key string) ([]*UpsertResult, error) {
-	req := &Upsert{
-		SObjects:            s,
-		ExternalIDFieldName: key,
-	}
-	res, err := c.soapClient.Upsert(req)
-	if err != nil {
+func (c *Client) Upsert(s []*SObject, key string, opts ...CallOption) ([]*UpsertResult, error) {
+	return c.UpsertContext(context.Background(), s, key, opts...)
+}
+
+func (c *Client) UpsertContext(ctx context.Context, s []*SObject, key string, opts ...CallOption) ([]*UpsertResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	results := make([]*UpsertResult, len(s))
+	err := chunked(ctx, len(s), func(ctx context.Context, start, end int) error {
+		res, err := c.soapClient.UpsertContext(ctx, &Upsert{SObjects: s[start:end], ExternalIDFieldName: key})
+		if err != nil {
+			return err
+		}
+		if err := chunkResults(start, end, len(res.Result)); err != nil {
+			return err
+		}
+		copy(results[start:end], res.Result)
+		return nil
+	})
+	if err != nil && !partial(err) {
 		return nil, err
 	}
-	return res.Result, nil
+	return results, err
 }
 
-func (c *Client) Merge(mergeReq []*MergeRequest) ([]*MergeResult, error) {
+func (c *Client) Merge(mergeReq []*MergeRequest, opts ...CallOption) ([]*MergeResult, error) {
+	return c.MergeContext(context.Background(), mergeReq, opts...)
+}
+
+func (c *Client) MergeContext(ctx context.Context, mergeReq []*MergeRequest, opts ...CallOption) ([]*MergeResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &Merge{
 		Request: mergeReq,
 	}
-	res, err := c.soapClient.Merge(req)
+	res, err := c.soapClient.MergeContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) Delete(ids []string) ([]*DeleteResult, error) {
-	req := &Delete{
-		Ids: ids,
-	}
-	res, err := c.soapClient.Delete(req)
-	if err != nil {
+func (c *Client) Delete(ids []string, opts ...CallOption) ([]*DeleteResult, error) {
+	return c.DeleteContext(context.Background(), ids, opts...)
+}
+
+func (c *Client) DeleteContext(ctx context.Context, ids []string, opts ...CallOption) ([]*DeleteResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	results := make([]*DeleteResult, len(ids))
+	err := chunked(ctx, len(ids), func(ctx context.Context, start, end int) error {
+		res, err := c.soapClient.DeleteContext(ctx, &Delete{Ids: ids[start:end]})
+		if err != nil {
+			return err
+		}
+		if err := chunkResults(start, end, len(res.Result)); err != nil {
+			return err
+		}
+		copy(results[start:end], res.Result)
+		return nil
+	})
+	if err != nil && !partial(err) {
 		return nil, err
 	}
-	return res.Result, nil
+	return results, err
 }
 
-func (c *Client) Undelete(ids []string) ([]*UndeleteResult, error) {
-	req := &Undelete{
-		Ids: ids,
+func (c *Client) Undelete(ids []string, opts ...CallOption) ([]*UndeleteResult, error) {
+	return c.UndeleteContext(context.Background(), ids, opts...)
+}
+
+func (c *Client) UndeleteContext(ctx context.Context, ids []string, opts ...CallOption) ([]*UndeleteResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	results := make([]*UndeleteResult, len(ids))
+	err := chunked(ctx, len(ids), func(ctx context.Context, start, end int) error {
+		res, err := c.soapClient.UndeleteContext(ctx, &Undelete{Ids: ids[start:end]})
+		if err != nil {
+			return err
+		}
+		if err := chunkResults(start, end, len(res.Result)); err != nil {
+			return err
+		}
+		copy(results[start:end], res.Result)
+		return nil
+	})
+	if err != nil && !partial(err) {
+		return nil, err
 	}
-	res, err := c.soapClient.Undelete(req)
-	if err != nil {
+	return results, err
+}
+
+func (c *Client) EmptyRecycleBin(ids []string, opts ...CallOption) ([]*EmptyRecycleBinResult, error) {
+	return c.EmptyRecycleBinContext(context.Background(), ids, opts...)
+}
+
+func (c *Client) EmptyRecycleBinContext(ctx context.Context, ids []string, opts ...CallOption) ([]*EmptyRecycleBinResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	results := make([]*EmptyRecycleBinResult, len(ids))
+	err := chunked(ctx, len(ids), func(ctx context.Context, start, end int) error {
+		res, err := c.soapClient.EmptyRecycleBinContext(ctx, &EmptyRecycleBin{Ids: ids[start:end]})
+		if err != nil {
+			return err
+		}
+		if err := chunkResults(start, end, len(res.Result)); err != nil {
+			return err
+		}
+		copy(results[start:end], res.Result)
+		return nil
+	})
+	if err != nil && !partial(err) {
 		return nil, err
 	}
-	return res.Result, nil
+	return results, err
+}
+
+func (c *Client) Retrieve(s string, ids []string, fieldList string, opts ...CallOption) ([]*SObject, error) {
+	return c.RetrieveContext(context.Background(), s, ids, fieldList, opts...)
 }
 
-func (c *Client) Retrieve(s string, ids []string, fieldList string) ([]*SObject, error) {
+func (c *Client) RetrieveContext(ctx context.Context, s string, ids []string, fieldList string, opts ...CallOption) ([]*SObject, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &Retrieve{
 		SObjectType: s,
 		Ids:         ids,
 		FieldList:   fieldList,
 	}
-	res, err := c.soapClient.Retrieve(req)
+	res, err := c.soapClient.RetrieveContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -270,15 +635,21 @@ func (c *Client) Retrieve(s string, ids []string, fieldList string) ([]*SObject,
 }
 
 func (c *Client) SetBatchSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.BatchSize = int32(size)
 	c.setHeaders()
 }
 
 func (c *Client) SetDebuggingHeader(categories []*LogInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.DebugCategories = categories
 	c.setHeaders()
 }
 
+// setHeaders replaces the default headers of the SOAP client. It must be
+// called with c.mu held.
 func (c *Client) setHeaders() {
 	var headers []interface{}
 	if c.DebugCategories != nil {
@@ -299,160 +670,250 @@ func (c *Client) setHeaders() {
 	c.soapClient.SetHeader(headers)
 }
 
-func (c *Client) Query(q string) (*QueryResult, error) {
+func (c *Client) Query(q string, opts ...CallOption) (*QueryResult, error) {
+	return c.QueryContext(context.Background(), q, opts...)
+}
+
+func (c *Client) QueryContext(ctx context.Context, q string, opts ...CallOption) (*QueryResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &Query{
 		QueryString: q,
 	}
-	res, err := c.soapClient.Query(req)
+	res, err := c.soapClient.QueryContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	if callOptionsFrom(ctx).fetchChildren && res.Result != nil {
+		if err := c.FetchChildren(ctx, res.Result.Records...); err != nil {
+			return nil, err
+		}
+	}
 	return res.Result, nil
 }
 
-func (c *Client) QueryAll(q string) (*QueryResult, error) {
+func (c *Client) QueryAll(q string, opts ...CallOption) (*QueryResult, error) {
+	return c.QueryAllContext(context.Background(), q, opts...)
+}
+
+func (c *Client) QueryAllContext(ctx context.Context, q string, opts ...CallOption) (*QueryResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &QueryAll{
 		QueryString: q,
 	}
-	res, err := c.soapClient.QueryAll(req)
+	res, err := c.soapClient.QueryAllContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	if callOptionsFrom(ctx).fetchChildren && res.Result != nil {
+		if err := c.FetchChildren(ctx, res.Result.Records...); err != nil {
+			return nil, err
+		}
+	}
 	return res.Result, nil
 }
 
-func (c *Client) QueryMore(ql string) (*QueryResult, error) {
+func (c *Client) QueryMore(ql string, opts ...CallOption) (*QueryResult, error) {
+	return c.QueryMoreContext(context.Background(), ql, opts...)
+}
+
+func (c *Client) QueryMoreContext(ctx context.Context, ql string, opts ...CallOption) (*QueryResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &QueryMore{
 		QueryLocator: ql,
 	}
-	res, err := c.soapClient.QueryMore(req)
+	res, err := c.soapClient.QueryMoreContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	if callOptionsFrom(ctx).fetchChildren && res.Result != nil {
+		if err := c.FetchChildren(ctx, res.Result.Records...); err != nil {
+			return nil, err
+		}
+	}
 	return res.Result, nil
 }
 
-func (c *Client) Search(s string) (*SearchResult, error) {
+func (c *Client) Search(s string, opts ...CallOption) (*SearchResult, error) {
+	return c.SearchContext(context.Background(), s, opts...)
+}
+
+func (c *Client) SearchContext(ctx context.Context, s string, opts ...CallOption) (*SearchResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &Search{
 		SearchString: s,
 	}
-	res, err := c.soapClient.Search(req)
+	res, err := c.soapClient.SearchContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) SetPassword(uid string, password string) (*SetPasswordResult, error) {
+func (c *Client) SetPassword(uid string, password string, opts ...CallOption) (*SetPasswordResult, error) {
+	return c.SetPasswordContext(context.Background(), uid, password, opts...)
+}
+
+func (c *Client) SetPasswordContext(ctx context.Context, uid string, password string, opts ...CallOption) (*SetPasswordResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &SetPassword{
 		UserId:   uid,
 		Password: password,
 	}
-	res, err := c.soapClient.SetPassword(req)
+	res, err := c.soapClient.SetPasswordContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) ResetPassword(uid string) (*ResetPasswordResult, error) {
+func (c *Client) ResetPassword(uid string, opts ...CallOption) (*ResetPasswordResult, error) {
+	return c.ResetPasswordContext(context.Background(), uid, opts...)
+}
+
+func (c *Client) ResetPasswordContext(ctx context.Context, uid string, opts ...CallOption) (*ResetPasswordResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &ResetPassword{
 		UserId: uid,
 	}
-	res, err := c.soapClient.ResetPassword(req)
+	res, err := c.soapClient.ResetPasswordContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) GetUserInfo() (*GetUserInfoResult, error) {
-	res, err := c.soapClient.GetUserInfo(&GetUserInfo{})
+func (c *Client) GetUserInfo(opts ...CallOption) (*GetUserInfoResult, error) {
+	return c.GetUserInfoContext(context.Background(), opts...)
+}
+
+func (c *Client) GetUserInfoContext(ctx context.Context, opts ...CallOption) (*GetUserInfoResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	res, err := c.soapClient.GetUserInfoContext(ctx, &GetUserInfo{})
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) SendEmailMessage(ids string) (*SendEmailResult, error) {
+func (c *Client) SendEmailMessage(ids string, opts ...CallOption) (*SendEmailResult, error) {
+	return c.SendEmailMessageContext(context.Background(), ids, opts...)
+}
+
+func (c *Client) SendEmailMessageContext(ctx context.Context, ids string, opts ...CallOption) (*SendEmailResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &SendEmailMessage{
 		Ids: ids,
 	}
-	res, err := c.soapClient.SendEmailMessage(req)
+	res, err := c.soapClient.SendEmailMessageContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) CompileAndTest(r *CompileAndTestRequest) (*CompileAndTestResult, error) {
+func (c *Client) CompileAndTest(r *CompileAndTestRequest, opts ...CallOption) (*CompileAndTestResult, error) {
+	return c.CompileAndTestContext(context.Background(), r, opts...)
+}
+
+func (c *Client) CompileAndTestContext(ctx context.Context, r *CompileAndTestRequest, opts ...CallOption) (*CompileAndTestResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &CompileAndTest{
 		CompileAndTestRequest: r,
 	}
-	res, err := c.soapClient.CompileAndTest(req)
+	res, err := c.soapClient.CompileAndTestContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) CompileClasses(scripts []string) ([]*CompileClassResult, error) {
+func (c *Client) CompileClasses(scripts []string, opts ...CallOption) ([]*CompileClassResult, error) {
+	return c.CompileClassesContext(context.Background(), scripts, opts...)
+}
+
+func (c *Client) CompileClassesContext(ctx context.Context, scripts []string, opts ...CallOption) ([]*CompileClassResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &CompileClasses{
 		Scripts: scripts,
 	}
-	res, err := c.soapClient.CompileClasses(req)
+	res, err := c.soapClient.CompileClassesContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) CompileTriggers(scripts []string) ([]*CompileTriggerResult, error) {
+func (c *Client) CompileTriggers(scripts []string, opts ...CallOption) ([]*CompileTriggerResult, error) {
+	return c.CompileTriggersContext(context.Background(), scripts, opts...)
+}
+
+func (c *Client) CompileTriggersContext(ctx context.Context, scripts []string, opts ...CallOption) ([]*CompileTriggerResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &CompileTriggers{
 		Scripts: scripts,
 	}
-	res, err := c.soapClient.CompileTriggers(req)
+	res, err := c.soapClient.CompileTriggersContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) ExecuteAnonymous(code string) (*ExecuteAnonymousResult, error) {
+func (c *Client) ExecuteAnonymous(code string, opts ...CallOption) (*ExecuteAnonymousResult, error) {
+	return c.ExecuteAnonymousContext(context.Background(), code, opts...)
+}
+
+func (c *Client) ExecuteAnonymousContext(ctx context.Context, code string, opts ...CallOption) (*ExecuteAnonymousResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &ExecuteAnonymous{
 		String: code,
 	}
-	res, err := c.soapClient.ExecuteAnonymous(req)
+	res, err := c.soapClient.ExecuteAnonymousContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) RunTests(r *RunTestsRequest) (*RunTestsResult, error) {
+func (c *Client) RunTests(r *RunTestsRequest, opts ...CallOption) (*RunTestsResult, error) {
+	return c.RunTestsContext(context.Background(), r, opts...)
+}
+
+func (c *Client) RunTestsContext(ctx context.Context, r *RunTestsRequest, opts ...CallOption) (*RunTestsResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &RunTests{
 		RunTestsRequest: r,
 	}
-	res, err := c.soapClient.RunTests(req)
+	res, err := c.soapClient.RunTestsContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) WsdlToApex(req *WsdlToApex) (*WsdlToApexResult, error) {
-	res, err := c.soapClient.WsdlToApex(req)
+func (c *Client) WsdlToApex(req *WsdlToApex, opts ...CallOption) (*WsdlToApexResult, error) {
+	return c.WsdlToApexContext(context.Background(), req, opts...)
+}
+
+func (c *Client) WsdlToApexContext(ctx context.Context, req *WsdlToApex, opts ...CallOption) (*WsdlToApexResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
+	res, err := c.soapClient.WsdlToApexContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return res.Result, nil
 }
 
-func (c *Client) SendEmail(m *Email) (*SendEmailResult, error) {
+func (c *Client) SendEmail(m *Email, opts ...CallOption) (*SendEmailResult, error) {
+	return c.SendEmailContext(context.Background(), m, opts...)
+}
+
+func (c *Client) SendEmailContext(ctx context.Context, m *Email, opts ...CallOption) (*SendEmailResult, error) {
+	ctx = WithCallOptions(ctx, opts...)
 	req := &SendEmail{
 		Messages: m,
 	}
-	res, err := c.soapClient.SendEmail(req)
+	res, err := c.soapClient.SendEmailContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -462,3 +923,9 @@ func (c *Client) SendEmail(m *Email) (*SendEmailResult, error) {
 func (c *Client) GetInfo() *LimitInfoHeader {
 	return c.soapClient.GetInfo()
 }
+
+// LastResponseHeaders returns the limits and debug log of the most recent
+// response. Use WithResponseHeaders to get the headers of a given call.
+func (c *Client) LastResponseHeaders() *ResponseHeaders {
+	return c.soapClient.LastResponseHeaders()
+}
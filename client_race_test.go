@@ -0,0 +1,170 @@
+package soapforce
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var sessionIdPattern = regexp.MustCompile(`<sessionId>([^<]*)</sessionId>`)
+
+// newRaceServer answers create, update, query and queryMore calls, and
+// fails the test if a request has no session header of the form sid-N.
+func newRaceServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, err := requestBody(r)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		if m := sessionIdPattern.FindStringSubmatch(request); m == nil || !strings.HasPrefix(m[1], "sid-") {
+			t.Errorf("request without session header: %s", request)
+		}
+
+		var result string
+		switch {
+		case strings.Contains(request, "<create"):
+			result = "<createResponse>" + strings.Repeat(saveResultXML, strings.Count(request, "<sObjects")) + "</createResponse>"
+		case strings.Contains(request, "<update"):
+			result = "<updateResponse>" + strings.Repeat(saveResultXML, strings.Count(request, "<sObjects")) + "</updateResponse>"
+		case strings.Contains(request, "<queryMore"):
+			result = "<queryMoreResponse>" + queryResultXML("true", "") + "</queryMoreResponse>"
+		case strings.Contains(request, "<query"):
+			result = "<queryResponse>" + queryResultXML("false", "01gxx0000000001-1") + "</queryResponse>"
+		default:
+			t.Errorf("unexpected request: %s", request)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeEnvelope(w, result)
+	}))
+}
+
+// requestBody returns the decompressed body of a SOAP request.
+func requestBody(r *http.Request) (string, error) {
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return "", err
+		}
+		body = gz
+	}
+	b, err := ioutil.ReadAll(body)
+	return string(b), err
+}
+
+// writeEnvelope writes a SOAP response with body as its content.
+func writeEnvelope(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>`+
+		`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="urn:partner.soap.sforce.com" xmlns:sf="urn:sobject.partner.soap.sforce.com" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">`+
+		`<soapenv:Body>%s</soapenv:Body></soapenv:Envelope>`, body)
+}
+
+const saveResultXML = `<result><id>001000000000001AAA</id><success>true</success></result>`
+
+func queryResultXML(done, locator string) string {
+	l := `<queryLocator xsi:nil="true"/>`
+	if locator != "" {
+		l = "<queryLocator>" + locator + "</queryLocator>"
+	}
+	return `<result><done>` + done + `</done>` + l +
+		`<records xsi:type="sf:sObject"><sf:type>Account</sf:type><sf:Id>001000000000001AAA</sf:Id><sf:Name>Acme</sf:Name></records>` +
+		`<size>1</size></result>`
+}
+
+// TestClientConcurrentCalls is meant to be run with -race: calls share the
+// client while its session, headers and debug settings change.
+func TestClientConcurrentCalls(t *testing.T) {
+	server := newRaceServer(t)
+	defer server.Close()
+
+	c := NewClient()
+	c.SetServerUrl(server.URL)
+	c.SetLogger(ioutil.Discard)
+	c.SetAccessToken("sid-0")
+
+	const workers, iterations = 8, 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				records := []*SObject{
+					{Type: "Account", Fields: map[string]interface{}{"Name": fmt.Sprintf("Acme %d-%d", i, j)}},
+					{Type: "Account", Fields: map[string]interface{}{"Name": "Other"}},
+				}
+				created, err := c.Create(records)
+				if err != nil {
+					t.Errorf("Create: %v", err)
+					return
+				}
+				if len(created) != len(records) {
+					t.Errorf("Create returned %d results, want %d", len(created), len(records))
+				}
+				records[0].Id = created[0].Id
+				if _, err := c.Update(records[:1]); err != nil {
+					t.Errorf("Update: %v", err)
+					return
+				}
+				res, err := c.Query("SELECT Id, Name FROM Account")
+				if err != nil {
+					t.Errorf("Query: %v", err)
+					return
+				}
+				if res.Done || res.QueryLocator == "" {
+					t.Errorf("Query returned done=%v locator=%q, want a locator", res.Done, res.QueryLocator)
+					return
+				}
+				more, err := c.QueryMore(res.QueryLocator)
+				if err != nil {
+					t.Errorf("QueryMore: %v", err)
+					return
+				}
+				if !more.Done || len(more.Records) != 1 {
+					t.Errorf("QueryMore returned done=%v and %d records", more.Done, len(more.Records))
+				}
+			}
+		}(i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 1; j <= workers*iterations; j++ {
+			c.SetAccessToken(fmt.Sprintf("sid-%d", j))
+			c.SetBatchSize(200 + j%2)
+			c.SetDebuggingHeader([]*LogInfo{{Category: LogCategoryApex_code, Level: LogCategoryLevelDebug}})
+			c.soapClient.AddHeader(&AllowFieldTruncationHeader{AllowFieldTruncation: j%2 == 0})
+			c.SetDebug(j%2 == 0)
+		}
+	}()
+	wg.Wait()
+}
+
+// TestSOAPClientHeadersCopiedOnWrite checks that a snapshot of the headers
+// is not changed by later writes.
+func TestSOAPClientHeadersCopiedOnWrite(t *testing.T) {
+	s := NewSOAPClient("", false, nil)
+	s.SetHeader(make([]interface{}, 0, 4))
+	s.AddHeader(&SessionHeader{SessionId: "a"})
+	snapshot := s.config().headers
+
+	s.AddHeader(&SessionHeader{SessionId: "b"})
+	s.SetHeader([]interface{}{&SessionHeader{SessionId: "c"}})
+	if len(snapshot) != 1 || snapshot[0].(*SessionHeader).SessionId != "a" {
+		t.Fatalf("snapshot changed to %v", snapshot)
+	}
+	if got := s.config().headers; len(got) != 1 || got[0].(*SessionHeader).SessionId != "c" {
+		t.Fatalf("headers = %v, want the session header c", got)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tzmfreedom/go-soapforce"
+)
+
+// generate returns the source of a file declaring one struct per describe
+// result. Every writable field is omitempty, so a struct only sends the
+// fields that were set. Nillable fields, and writable booleans and numbers,
+// are pointers so that false and 0 can be sent. Fields are cleared by
+// listing them in FieldsToNull. Parent and child relationships are readonly.
+func generate(pkg string, results []*soapforce.DescribeSObjectResult) ([]byte, error) {
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	g := &generator{objects: map[string]bool{}, imports: map[string]bool{}}
+	for _, r := range results {
+		g.objects[r.Name] = true
+	}
+	for _, r := range results {
+		g.object(r)
+	}
+
+	out := new(bytes.Buffer)
+	fmt.Fprintf(out, "// Code generated by soapforce-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(g.imports) > 0 {
+		var std, other []string
+		for p := range g.imports {
+			if strings.Contains(strings.Split(p, "/")[0], ".") {
+				other = append(other, p)
+			} else {
+				std = append(std, p)
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(other)
+		fmt.Fprintln(out, "import (")
+		for _, p := range std {
+			fmt.Fprintf(out, "\t%q\n", p)
+		}
+		if len(std) > 0 && len(other) > 0 {
+			fmt.Fprintln(out)
+		}
+		for _, p := range other {
+			fmt.Fprintf(out, "\t%q\n", p)
+		}
+		fmt.Fprintln(out, ")")
+	}
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	objects map[string]bool
+	imports map[string]bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) object(r *soapforce.DescribeSObjectResult) {
+	typeName := exported(r.Name)
+	names := map[string]bool{}
+	unique := func(name string) string {
+		for names[name] {
+			name += "_"
+		}
+		names[name] = true
+		return name
+	}
+
+	var picklists bytes.Buffer
+	g.printf("\n// %s is the %s object.\n", typeName, r.Label)
+	g.printf("type %s struct {\n", typeName)
+	g.printf("\t%s []string `sf:\",fieldsToNull\"`\n", unique("FieldsToNull"))
+	for _, f := range r.Fields {
+		goType := g.fieldType(f)
+		if goType == "" {
+			continue
+		}
+		if isPicklist(f) && len(f.PicklistValues) > 0 {
+			picklistType := typeName + "_" + exported(f.Name)
+			g.picklist(&picklists, picklistType, f)
+			if *f.Type_ == soapforce.FieldTypeMultipicklist {
+				goType = "[]" + picklistType
+			} else {
+				goType = picklistType
+			}
+		}
+		pointer := f.Nillable || !readOnly(f) && isBoolOrNumber(goType)
+		if pointer && !strings.HasPrefix(goType, "[]") && !strings.HasPrefix(goType, "*") {
+			goType = "*" + goType
+		}
+		g.printf("\t%s %s `sf:\"%s\"`\n", unique(exported(f.Name)), goType, tag(f.Name, readOnly(f)))
+
+		if f.RelationshipName == "" {
+			continue
+		}
+		parent := "*soapforce.SObject"
+		if len(f.ReferenceTo) == 1 && g.objects[f.ReferenceTo[0]] {
+			parent = "*" + exported(f.ReferenceTo[0])
+		} else {
+			g.imports["github.com/tzmfreedom/go-soapforce"] = true
+		}
+		// the parent is set through the lookup field, a parent read with
+		// a query can't be sent along with it
+		g.printf("\t%s %s `sf:\"%s\"`\n", unique(exported(f.RelationshipName)), parent, tag(f.RelationshipName, true))
+	}
+	for _, c := range r.ChildRelationships {
+		if c.RelationshipName == "" || !g.objects[c.ChildSObject] {
+			continue
+		}
+		g.printf("\t%s []*%s `sf:\"%s\"`\n", unique(exported(c.RelationshipName)), exported(c.ChildSObject), tag(c.RelationshipName, true))
+	}
+	g.printf("}\n\n")
+	g.printf("func (%s) SObjectType() string { return %q }\n", typeName, r.Name)
+	g.buf.Write(picklists.Bytes())
+}
+
+func (g *generator) picklist(buf *bytes.Buffer, typeName string, f *soapforce.Field) {
+	fmt.Fprintf(buf, "\n// %s is a value of %s.\n", typeName, f.Name)
+	fmt.Fprintf(buf, "type %s string\n\nconst (\n", typeName)
+	names := map[string]bool{}
+	for _, v := range f.PicklistValues {
+		if !v.Active {
+			continue
+		}
+		name := typeName + "_" + identifier(v.Value)
+		for names[name] {
+			name += "_"
+		}
+		names[name] = true
+		fmt.Fprintf(buf, "\t%s %s = %q\n", name, typeName, v.Value)
+	}
+	fmt.Fprintf(buf, ")\n")
+}
+
+// fieldType maps the SOAP type of a field to a Go type. Fields of unknown
+// types are skipped.
+func (g *generator) fieldType(f *soapforce.Field) string {
+	if f.SoapType == nil {
+		return ""
+	}
+	soapType := string(*f.SoapType)
+	if i := strings.LastIndex(soapType, ":"); i >= 0 {
+		soapType = soapType[i+1:]
+	}
+	switch soapType {
+	case "ID", "string", "time":
+		return "string"
+	case "boolean":
+		return "bool"
+	case "int":
+		return "int"
+	case "long":
+		return "int64"
+	case "double":
+		return "float64"
+	case "date":
+		g.imports["github.com/tzmfreedom/go-soapforce"] = true
+		return "soapforce.Date"
+	case "dateTime":
+		g.imports["time"] = true
+		return "time.Time"
+	case "base64Binary":
+		return "[]byte"
+	case "address":
+		g.imports["github.com/tzmfreedom/go-soapforce"] = true
+		return "*soapforce.Address"
+	case "location":
+		g.imports["github.com/tzmfreedom/go-soapforce"] = true
+		return "*soapforce.Location"
+	case "anyType":
+		return "interface{}"
+	case "JunctionIdListNames":
+		return "[]string"
+	}
+	return ""
+}
+
+func isBoolOrNumber(goType string) bool {
+	switch goType {
+	case "bool", "int", "int64", "float64":
+		return true
+	}
+	return false
+}
+
+func isPicklist(f *soapforce.Field) bool {
+	return f.Type_ != nil && (*f.Type_ == soapforce.FieldTypePicklist || *f.Type_ == soapforce.FieldTypeMultipicklist)
+}
+
+// readOnly reports whether a field can't be sent in create or update. The Id
+// is writable as it identifies the record to update.
+func readOnly(f *soapforce.Field) bool {
+	return f.Name != "Id" && !f.Createable && !f.Updateable
+}
+
+func tag(name string, readOnly bool) string {
+	if readOnly {
+		return name + ",readonly"
+	}
+	return name + ",omitempty"
+}
+
+// exported turns an API name such as ns__Field__c into an exported Go
+// identifier.
+func exported(name string) string {
+	name = identifier(name)
+	r := []rune(name)
+	if unicode.IsLetter(r[0]) {
+		r[0] = unicode.ToUpper(r[0])
+		return string(r)
+	}
+	return "X" + name
+}
+
+// identifier replaces the characters that can't appear in a Go identifier.
+func identifier(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
@@ -0,0 +1,193 @@
+// Command soapforce-gen generates Go structs for SObjects from their
+// describe metadata, for use with soapforce.Marshal and soapforce.Unmarshal.
+//
+// Describe the objects of an org:
+//
+//	SALESFORCE_USERNAME=... SALESFORCE_PASSWORD=... soapforce-gen -objects Account,Contact -o sobjects.go
+//
+// or read the describe results saved with -save, or the JSON returned by
+// the REST describe resource, to run without an org:
+//
+//	soapforce-gen -describe describe.json -package sobjects -o sobjects.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tzmfreedom/go-soapforce"
+)
+
+func main() {
+	var (
+		objects      = flag.String("objects", "", "comma separated SObject names to describe")
+		describeFile = flag.String("describe", "", "read describe results from a JSON file instead of logging in")
+		saveFile     = flag.String("save", "", "write the describe results to a JSON file")
+		output       = flag.String("o", "", "output file (default stdout)")
+		pkg          = flag.String("package", "sobjects", "package name of the generated file")
+		loginUrl     = flag.String("login-url", soapforce.DefaultLoginUrl, "login host, e.g. test.salesforce.com")
+		apiVersion   = flag.String("api-version", soapforce.DefaultApiVersion, "API version")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("soapforce-gen: ")
+
+	var results []*soapforce.DescribeSObjectResult
+	var err error
+	if *describeFile != "" {
+		results, err = readDescribe(*describeFile)
+	} else {
+		results, err = describe(*loginUrl, *apiVersion, splitObjects(*objects))
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *saveFile != "" {
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(*saveFile, b, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	src, err := generate(*pkg, results)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func splitObjects(s string) []string {
+	var objects []string
+	for _, o := range strings.Split(s, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+// describe logs in with SALESFORCE_USERNAME and SALESFORCE_PASSWORD and
+// describes objects, 100 at a time.
+func describe(loginUrl, apiVersion string, objects []string) ([]*soapforce.DescribeSObjectResult, error) {
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no objects given, use -objects or -describe")
+	}
+	client := soapforce.NewClient()
+	client.SetLoginUrl(loginUrl)
+	client.SetApiVersion(apiVersion)
+	if _, err := client.Login(os.Getenv("SALESFORCE_USERNAME"), os.Getenv("SALESFORCE_PASSWORD")); err != nil {
+		return nil, err
+	}
+	var results []*soapforce.DescribeSObjectResult
+	for len(objects) > 0 {
+		n := len(objects)
+		if n > 100 {
+			n = 100
+		}
+		res, err := client.DescribeSObjects(objects[:n])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res...)
+		objects = objects[n:]
+	}
+	return results, nil
+}
+
+// readDescribe reads a describe result, or a list of them, from a JSON
+// file written by -save or by the REST describe resource.
+func readDescribe(path string) ([]*soapforce.DescribeSObjectResult, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if !bytes.HasPrefix(b, []byte("[")) {
+		b = append(append([]byte("["), b...), ']')
+	}
+	var objects []describeJSON
+	if err := json.Unmarshal(b, &objects); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	results := make([]*soapforce.DescribeSObjectResult, len(objects))
+	for i, o := range objects {
+		results[i] = o.result()
+	}
+	return results, nil
+}
+
+// describeJSON is the part of a describe result used to generate the
+// structs. JSON names are matched case-insensitively, so it reads the files
+// written by -save as well as the REST describe resource, whose other
+// properties differ from the SOAP ones and are ignored.
+type describeJSON struct {
+	Name   string
+	Label  string
+	Fields []struct {
+		Name             string
+		SoapType         soapforce.SoapType
+		Type_            soapforce.FieldType
+		RestType         soapforce.FieldType `json:"type"`
+		Nillable         bool
+		Createable       bool
+		Updateable       bool
+		ReferenceTo      []string
+		RelationshipName string
+		PicklistValues   []struct {
+			Active bool
+			Value  string
+		}
+	}
+	ChildRelationships []struct {
+		ChildSObject     string
+		RelationshipName string
+	}
+}
+
+func (o *describeJSON) result() *soapforce.DescribeSObjectResult {
+	r := &soapforce.DescribeSObjectResult{Name: o.Name, Label: o.Label}
+	for _, f := range o.Fields {
+		field := &soapforce.Field{
+			Name:             f.Name,
+			Nillable:         f.Nillable,
+			Createable:       f.Createable,
+			Updateable:       f.Updateable,
+			ReferenceTo:      f.ReferenceTo,
+			RelationshipName: f.RelationshipName,
+		}
+		if f.SoapType != "" {
+			soapType := f.SoapType
+			field.SoapType = &soapType
+		}
+		fieldType := f.Type_
+		if fieldType == "" {
+			fieldType = f.RestType
+		}
+		if fieldType != "" {
+			field.Type_ = &fieldType
+		}
+		for _, v := range f.PicklistValues {
+			field.PicklistValues = append(field.PicklistValues, &soapforce.PicklistEntry{Active: v.Active, Value: v.Value})
+		}
+		r.Fields = append(r.Fields, field)
+	}
+	for _, c := range o.ChildRelationships {
+		r.ChildRelationships = append(r.ChildRelationships, &soapforce.ChildRelationship{ChildSObject: c.ChildSObject, RelationshipName: c.RelationshipName})
+	}
+	return r
+}
@@ -0,0 +1,92 @@
+package soapforce
+
+import (
+	"context"
+)
+
+// CredentialProvider authenticates a Client again after its session has
+// expired. Authenticate must leave the new session on c, e.g. by calling
+// one of its login methods.
+type CredentialProvider interface {
+	Authenticate(ctx context.Context, c *Client) error
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context, c *Client) error
+
+func (f CredentialProviderFunc) Authenticate(ctx context.Context, c *Client) error {
+	return f(ctx, c)
+}
+
+// PasswordCredentials re-authenticates with the SOAP login call.
+func PasswordCredentials(username, password string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, c *Client) error {
+		_, err := c.LoginContext(ctx, username, password)
+		return err
+	})
+}
+
+// OAuthPasswordCredentials re-authenticates with the OAuth username-password flow.
+func OAuthPasswordCredentials(username, password string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, c *Client) error {
+		_, err := c.LoginWithOAuthContext(ctx, username, password)
+		return err
+	})
+}
+
+// RefreshTokenCredentials re-authenticates with an OAuth refresh token.
+// Once the client has a token with a refresh token, e.g. after the org
+// rotated it, that one is used instead.
+func RefreshTokenCredentials(refreshToken string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, c *Client) error {
+		current := refreshToken
+		if token := c.Token(); token != nil && token.RefreshToken != "" {
+			current = token.RefreshToken
+		}
+		_, err := c.RefreshContext(ctx, current)
+		return err
+	})
+}
+
+// JWTCredentials re-authenticates with the OAuth JWT bearer flow.
+func JWTCredentials(config *JWTConfig) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, c *Client) error {
+		_, err := c.LoginWithJWTContext(ctx, config)
+		return err
+	})
+}
+
+// SetCredentialProvider enables automatic re-authentication. When a call
+// fails with INVALID_SESSION_ID, p is asked for a new session once and the
+// call is replayed. Passing nil disables it.
+func (c *Client) SetCredentialProvider(p CredentialProvider) {
+	c.authMu.Lock()
+	c.credentials = p
+	c.authMu.Unlock()
+	if p == nil {
+		c.soapClient.SetSessionRenewer(nil)
+		return
+	}
+	c.soapClient.SetSessionRenewer(c.renewSession)
+}
+
+// renewSession is shared by every caller that saw the same stale session,
+// so concurrent failures trigger a single re-authentication.
+func (c *Client) renewSession(ctx context.Context, headers []interface{}) error {
+	stale := ""
+	for _, h := range headers {
+		if sh, ok := h.(*SessionHeader); ok {
+			stale = sh.SessionId
+		}
+	}
+
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.credentials == nil {
+		return nil
+	}
+	if c.settings().SessionId != stale {
+		return nil
+	}
+	return c.credentials.Authenticate(ctx, c)
+}
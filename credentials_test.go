@@ -0,0 +1,102 @@
+package soapforce
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const invalidSessionFaultXML = `<soapenv:Fault><faultcode>sf:INVALID_SESSION_ID</faultcode>` +
+	`<faultstring>INVALID_SESSION_ID: Invalid Session ID found in SessionHeader</faultstring>` +
+	`<detail><fns:UnexpectedErrorFault xmlns:fns="urn:fault.partner.soap.sforce.com" xsi:type="fns:UnexpectedErrorFault">` +
+	`<fns:exceptionCode>INVALID_SESSION_ID</fns:exceptionCode>` +
+	`<fns:exceptionMessage>Invalid Session ID found in SessionHeader</fns:exceptionMessage>` +
+	`</fns:UnexpectedErrorFault></detail></soapenv:Fault>`
+
+// TestSessionRenewalIsShared checks that calls failing together with
+// INVALID_SESSION_ID refresh the session once, and are replayed with the new
+// session on the new instance.
+func TestSessionRenewalIsShared(t *testing.T) {
+	const workers = 8
+	var refreshes, stale, replayed int32
+	allStale := make(chan struct{})
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/oauth2/token" {
+			r.ParseForm()
+			if r.PostForm.Get("refresh_token") != "refresh-1" {
+				t.Errorf("refresh_token = %q", r.PostForm.Get("refresh_token"))
+			}
+			atomic.AddInt32(&refreshes, 1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token":"sid-renewed","instance_url":"%s/renewed","id":"%s/id/00Dxx/005xx","issued_at":"1700000000000","token_type":"Bearer"}`, server.URL, server.URL)
+			return
+		}
+		request, err := requestBody(r)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		if strings.Contains(request, "<sessionId>sid-stale</sessionId>") {
+			// answer once every worker has sent its call with the stale
+			// session, so that they all fail together
+			if atomic.AddInt32(&stale, 1) == workers {
+				close(allStale)
+			}
+			select {
+			case <-allStale:
+			case <-time.After(5 * time.Second):
+				t.Error("timed out waiting for the other calls")
+			}
+			writeEnvelope(w, invalidSessionFaultXML)
+			return
+		}
+		if !strings.Contains(request, "<sessionId>sid-renewed</sessionId>") || !strings.HasPrefix(r.URL.Path, "/renewed/services/Soap/u/") {
+			t.Errorf("replayed to %s with %s", r.URL.Path, request)
+		}
+		atomic.AddInt32(&replayed, 1)
+		writeEnvelope(w, "<queryResponse>"+queryResultXML("true", "")+"</queryResponse>")
+	}))
+	defer server.Close()
+
+	c := NewClient()
+	c.SetLogger(ioutil.Discard)
+	c.SetLoginUrl(server.URL)
+	c.SetServerUrl(server.URL + "/stale/services/Soap/u/" + DefaultApiVersion)
+	c.SetAccessToken("sid-stale")
+	c.SetCredentialProvider(RefreshTokenCredentials("refresh-1"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := c.Query("SELECT Id, Name FROM Account")
+			if err != nil {
+				t.Errorf("Query: %v", err)
+				return
+			}
+			if len(res.Records) != 1 {
+				t.Errorf("Query returned %d records", len(res.Records))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshes != 1 {
+		t.Errorf("session was renewed %d times, want once", refreshes)
+	}
+	if replayed != workers {
+		t.Errorf("%d calls were replayed, want %d", replayed, workers)
+	}
+	if got, want := c.soapClient.client.GetServerUrl(), server.URL+"/renewed/services/Soap/u/"+DefaultApiVersion; got != want {
+		t.Errorf("server URL = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+package soapforce
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// faultTypes maps the element names found inside <detail> to the fault
+// types generated from the partner WSDL.
+var faultTypes = map[string]func() error{
+	"ApiFault":                 func() error { return &ApiFault{} },
+	"ApiQueryFault":            func() error { return &ApiQueryFault{} },
+	"LoginFault":               func() error { return &LoginFault{} },
+	"InvalidQueryLocatorFault": func() error { return &InvalidQueryLocatorFault{} },
+	"InvalidNewPasswordFault":  func() error { return &InvalidNewPasswordFault{} },
+	"InvalidIdFault":           func() error { return &InvalidIdFault{} },
+	"UnexpectedErrorFault":     func() error { return &UnexpectedErrorFault{} },
+	"InvalidFieldFault":        func() error { return &InvalidFieldFault{} },
+	"InvalidSObjectFault":      func() error { return &InvalidSObjectFault{} },
+	"MalformedQueryFault":      func() error { return &MalformedQueryFault{} },
+	"MalformedSearchFault":     func() error { return &MalformedSearchFault{} },
+}
+
+// SOAPFaultDetail holds the typed fault decoded from the <detail> element
+// of a SOAP fault.
+type SOAPFaultDetail struct {
+	Fault error
+}
+
+// genericFault decodes fault elements that have no generated type.
+type genericFault struct {
+	ExceptionCode *ExceptionCode `xml:"exceptionCode,omitempty"`
+
+	ExceptionMessage string `xml:"exceptionMessage,omitempty"`
+
+	ExtendedErrorDetails []*ExtendedErrorDetails `xml:"extendedErrorDetails,omitempty"`
+
+	Row int32 `xml:"row,omitempty"`
+
+	Column int32 `xml:"column,omitempty"`
+}
+
+func (d *SOAPFaultDetail) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if d.Fault != nil {
+				if err := dec.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if newFault, ok := faultTypes[t.Name.Local]; ok {
+				f := newFault()
+				if err := dec.DecodeElement(f, &t); err != nil {
+					return err
+				}
+				d.Fault = f
+				continue
+			}
+			g := &genericFault{}
+			if err := dec.DecodeElement(g, &t); err != nil {
+				return err
+			}
+			apiFault := &ApiFault{
+				ExceptionCode:        g.ExceptionCode,
+				ExceptionMessage:     g.ExceptionMessage,
+				ExtendedErrorDetails: g.ExtendedErrorDetails,
+			}
+			if g.Row != 0 || g.Column != 0 {
+				d.Fault = &ApiQueryFault{ApiFault: apiFault, Row: g.Row, Column: g.Column}
+			} else {
+				d.Fault = apiFault
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (f *SOAPFault) Unwrap() error {
+	if f.Detail == nil {
+		return nil
+	}
+	return f.Detail.Fault
+}
+
+// Is reports whether the fault carries the given ExceptionCode. It falls
+// back to the faultcode when the fault has no detail.
+func (f *SOAPFault) Is(target error) bool {
+	code, ok := target.(ExceptionCode)
+	if !ok {
+		return false
+	}
+	return strings.TrimPrefix(f.Code, "sf:") == string(code)
+}
+
+func (c ExceptionCode) Error() string {
+	return string(c)
+}
+
+func (f *ApiFault) Error() string {
+	if f == nil {
+		return ""
+	}
+	if f.ExceptionCode == nil {
+		return f.ExceptionMessage
+	}
+	return fmt.Sprintf("%s: %s", *f.ExceptionCode, f.ExceptionMessage)
+}
+
+// Is makes errors.Is(err, ExceptionCodeINVALID_SESSION_ID) and friends work
+// for every fault type.
+func (f *ApiFault) Is(target error) bool {
+	code, ok := target.(ExceptionCode)
+	if !ok {
+		return false
+	}
+	return f != nil && f.ExceptionCode != nil && *f.ExceptionCode == code
+}
+
+// As lets errors.As extract the embedded *ApiFault from any of the more
+// specific fault types.
+func (f *ApiFault) As(target interface{}) bool {
+	if t, ok := target.(**ApiFault); ok && f != nil {
+		*t = f
+		return true
+	}
+	return false
+}
+
+func (f *ApiQueryFault) Error() string {
+	if f == nil {
+		return ""
+	}
+	if f.ApiFault == nil {
+		return fmt.Sprintf("row %d, column %d", f.Row, f.Column)
+	}
+	return f.ApiFault.Error()
+}
+
+func (f *ApiQueryFault) Is(target error) bool {
+	return f != nil && f.ApiFault.Is(target)
+}
+
+func (f *ApiQueryFault) As(target interface{}) bool {
+	if f == nil {
+		return false
+	}
+	if t, ok := target.(**ApiQueryFault); ok {
+		*t = f
+		return true
+	}
+	return f.ApiFault.As(target)
+}
@@ -0,0 +1,408 @@
+package soapforce
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+	dateLayout     = "2006-01-02"
+)
+
+// Date is a field value sent as xsd:date. A time.Time is sent as
+// xsd:dateTime.
+type Date struct {
+	time.Time
+}
+
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	t, err := time.Parse(dateLayout, string(b))
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
+
+// encodeSObjectFields writes the content of an sObject element. Only the
+// fields listed in FieldsToNull are cleared: nil values, and the null
+// fields of a queried record, are not sent. Parent records, subquery
+// results and compound fields returned by a query are skipped too, so a
+// record read with a query can be passed to Update. Fields are written in
+// name order so the same record always produces the same envelope.
+func encodeSObjectFields(e *xml.Encoder, s *SObject) error {
+	if err := e.EncodeElement(s.Type, xml.StartElement{Name: xml.Name{Local: "type"}}); err != nil {
+		return err
+	}
+	if s.Id != "" {
+		if err := e.EncodeElement(s.Id, xml.StartElement{Name: xml.Name{Local: "Id"}}); err != nil {
+			return err
+		}
+	}
+	for _, v := range s.FieldsToNull {
+		if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "fieldsToNull"}}); err != nil {
+			return err
+		}
+	}
+	names := make([]string, 0, len(s.Fields))
+	for k := range s.Fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		v := s.Fields[k]
+		if isNilValue(v) || s.decodedNull(k) || s.decodedCompound(k) {
+			continue
+		}
+		switch v := v.(type) {
+		case *QueryResult, *Address, *Location:
+			// subquery results and compound fields returned by a query
+			// can't be written
+			continue
+		case *SObject:
+			if v.related {
+				// a parent returned by a query is not a reference
+				continue
+			}
+		}
+		if err := encodeField(e, k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodedNull reports whether a field still has the empty value it was
+// decoded with from xsi:nil.
+func (s *SObject) decodedNull(name string) bool {
+	t, ok := s.types[name]
+	return ok && t.null && s.Fields[name] == ""
+}
+
+// decodedCompound reports whether a field is an address or location whose
+// types have not been converted yet.
+func (s *SObject) decodedCompound(name string) bool {
+	t, ok := s.types[name]
+	return ok && t.compound != nil && s.Fields[name] == ""
+}
+
+// encodeField writes v as the element name in Salesforce wire format.
+// Nested *SObject and map values are written as relationship references,
+// e.g. {"Account": map[string]interface{}{"type": "Account", "Ext__c": "1"}}.
+func encodeField(e *xml.Encoder, name string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch v := v.(type) {
+	case *SObject:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		// a reference only identifies the related record
+		ref := *v
+		ref.FieldsToNull = nil
+		if err := encodeSObjectFields(e, &ref); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	case SObject:
+		return encodeField(e, name, &v)
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[k] = value
+		}
+		return encodeField(e, name, m)
+	case map[string]interface{}:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		if t, ok := v["type"]; ok {
+			if err := encodeField(e, "type", t); err != nil {
+				return err
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			if k != "type" && !isNilValue(v[k]) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeField(e, k, v[k]); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	}
+
+	text, err := formatFieldValue(v)
+	if err != nil {
+		return fmt.Errorf("soapforce: field %s: %v", name, err)
+	}
+	return e.EncodeElement(text, start)
+}
+
+// formatFieldValue returns the text of a scalar field value.
+func formatFieldValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format(dateTimeLayout), nil
+	case *time.Time:
+		return v.Format(dateTimeLayout), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case []string:
+		// multi-select picklist
+		return strings.Join(v, ";"), nil
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		return string(b), err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		return formatFieldValue(rv.Elem().Interface())
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("cannot encode %v", f)
+		}
+		return strconv.FormatFloat(f, 'f', -1, rv.Type().Bits()), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.String {
+			items := make([]string, rv.Len())
+			for i := range items {
+				items[i] = rv.Index(i).String()
+			}
+			return strings.Join(items, ";"), nil
+		}
+	}
+	return "", fmt.Errorf("cannot encode value of type %T", v)
+}
+
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// fieldType is what the decoder saw of a field besides its text.
+type fieldType struct {
+	xsiType  string
+	null     bool
+	compound interface{}
+}
+
+func (s *SObject) setFieldType(name string, t fieldType) {
+	if s.types == nil {
+		s.types = make(map[string]fieldType)
+	}
+	s.types[name] = t
+}
+
+// ConvertFieldTypes replaces the string values of a decoded record with
+// values of the Go type matching their xsi:type: int, int64, float64, bool,
+// time.Time, Date, []byte, *Address or *Location. Fields sent as xsi:nil
+// become nil, while empty strings are kept. Related records and subquery
+// results are converted too. Calling it again has no effect.
+func (s *SObject) ConvertFieldTypes() error {
+	for name, t := range s.types {
+		switch {
+		case t.null:
+			s.Fields[name] = nil
+		case t.compound != nil:
+			s.Fields[name] = t.compound
+		default:
+			text, _ := s.Fields[name].(string)
+			v, err := typedValue(t.xsiType, text)
+			if err != nil {
+				return fmt.Errorf("soapforce: field %s: %v", name, err)
+			}
+			s.Fields[name] = v
+		}
+	}
+	s.types = nil
+	for _, v := range s.Fields {
+		switch v := v.(type) {
+		case *SObject:
+			if err := v.ConvertFieldTypes(); err != nil {
+				return err
+			}
+		case *QueryResult:
+			for _, r := range v.Records {
+				if err := r.ConvertFieldTypes(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func localType(xsiType string) string {
+	if i := strings.LastIndex(xsiType, ":"); i >= 0 {
+		return xsiType[i+1:]
+	}
+	return xsiType
+}
+
+func typedValue(xsiType, text string) (interface{}, error) {
+	switch localType(xsiType) {
+	case "int":
+		return strconv.Atoi(text)
+	case "long":
+		return strconv.ParseInt(text, 10, 64)
+	case "double":
+		return strconv.ParseFloat(text, 64)
+	case "boolean":
+		return strconv.ParseBool(text)
+	case "dateTime":
+		return time.Parse(time.RFC3339Nano, text)
+	case "date":
+		var d Date
+		err := d.UnmarshalText([]byte(text))
+		return d, err
+	case "base64Binary":
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return text, nil
+}
+
+func isCompoundType(xsiType string) bool {
+	switch localType(xsiType) {
+	case "address", "location":
+		return true
+	}
+	return false
+}
+
+// compoundField mirrors Address and Location without their XMLName, so it
+// can be decoded from an element of any name.
+type compoundField struct {
+	City            string `xml:"city"`
+	Country         string `xml:"country"`
+	CountryCode     string `xml:"countryCode"`
+	GeocodeAccuracy string `xml:"geocodeAccuracy"`
+	PostalCode      string `xml:"postalCode"`
+	State           string `xml:"state"`
+	StateCode       string `xml:"stateCode"`
+	Street          string `xml:"street"`
+	Latitude        string `xml:"latitude"`
+	Longitude       string `xml:"longitude"`
+}
+
+func decodeCompound(d *xml.Decoder, start *xml.StartElement, xsiType string) (interface{}, error) {
+	var c compoundField
+	if err := d.DecodeElement(&c, start); err != nil {
+		return nil, err
+	}
+	var location *Location
+	if c.Latitude != "" || c.Longitude != "" {
+		location = &Location{}
+		var err error
+		if c.Latitude != "" {
+			if location.Latitude, err = strconv.ParseFloat(c.Latitude, 64); err != nil {
+				return nil, err
+			}
+		}
+		if c.Longitude != "" {
+			if location.Longitude, err = strconv.ParseFloat(c.Longitude, 64); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if localType(xsiType) == "location" {
+		if location == nil {
+			location = &Location{}
+		}
+		return location, nil
+	}
+	return &Address{
+		Location:        location,
+		City:            c.City,
+		Country:         c.Country,
+		CountryCode:     c.CountryCode,
+		GeocodeAccuracy: c.GeocodeAccuracy,
+		PostalCode:      c.PostalCode,
+		State:           c.State,
+		StateCode:       c.StateCode,
+		Street:          c.Street,
+	}, nil
+}
+
+// convertFieldTypes calls ConvertFieldTypes on every record in a decoded
+// response.
+func convertFieldTypes(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if s, ok := v.Interface().(*SObject); ok {
+			return s.ConvertFieldTypes()
+		}
+		return convertFieldTypes(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				if err := convertFieldTypes(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Slice, reflect.Array:
+		default:
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := convertFieldTypes(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
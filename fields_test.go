@@ -0,0 +1,63 @@
+package soapforce
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const queriedContactXML = `<records xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:sf="urn:sobject.partner.soap.sforce.com" xsi:type="sf:sObject">` +
+	`<sf:type>Contact</sf:type>` +
+	`<sf:Id>003000000000001AAA</sf:Id>` +
+	`<sf:LastName>Smith</sf:LastName>` +
+	`<sf:Phone xsi:nil="true"/>` +
+	`<sf:AccountId>001000000000001AAA</sf:AccountId>` +
+	`<sf:Account xsi:type="sf:sObject"><sf:type>Account</sf:type><sf:Id xsi:nil="true"/><sf:Name>Acme</sf:Name></sf:Account>` +
+	`<sf:MailingAddress xsi:type="address"><sf:city>Paris</sf:city></sf:MailingAddress>` +
+	`<sf:Cases xsi:type="QueryResult"><sf:done>true</sf:done><sf:queryLocator xsi:nil="true"/><sf:size>0</sf:size></sf:Cases>` +
+	`</records>`
+
+// TestEncodeQueriedRecord checks that a record read with a query only sends
+// its own writable fields when it is updated.
+func TestEncodeQueriedRecord(t *testing.T) {
+	for _, typed := range []bool{false, true} {
+		var s SObject
+		if err := xml.Unmarshal([]byte(queriedContactXML), &s); err != nil {
+			t.Fatal(err)
+		}
+		if typed {
+			if err := s.ConvertFieldTypes(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.Fields["LastName"] = "Jones"
+		b, err := xml.Marshal(&s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(b)
+		want := `<sObjects xmlns="urn:sobject.partner.soap.sforce.com"><type>Contact</type><Id>003000000000001AAA</Id>` +
+			`<AccountId>001000000000001AAA</AccountId><LastName>Jones</LastName></sObjects>`
+		if got != want {
+			t.Errorf("typed=%v:\ngot  %s\nwant %s", typed, got, want)
+		}
+	}
+}
+
+func TestEncodeReference(t *testing.T) {
+	s := &SObject{
+		Type:         "Contact",
+		FieldsToNull: []string{"Phone"},
+		Fields: map[string]interface{}{
+			"LastName": "Smith",
+			"Account":  &SObject{Type: "Account", Fields: map[string]interface{}{"Ext__c": "A-1"}},
+		},
+	}
+	b, err := xml.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<Account><type>Account</type><Ext__c>A-1</Ext__c></Account>`; !strings.Contains(string(b), want) {
+		t.Errorf("got %s, want the reference %s", b, want)
+	}
+}
@@ -0,0 +1,72 @@
+package soapforce
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// CallInfo describes a single SOAP round trip. Operation, Request, Headers
+// and Response are set before the first interceptor runs; RequestEnvelope,
+// StatusCode, ResponseEnvelope and ResponseHeaders are filled in once the
+// request has been sent. Interceptors may change Request and Headers before
+// calling next.
+type CallInfo struct {
+	Operation string
+	Request   interface{}
+	Headers   []interface{}
+
+	RequestEnvelope  []byte
+	StatusCode       int
+	ResponseEnvelope []byte
+
+	// Response is the value the response body is decoded into.
+	Response interface{}
+
+	// ResponseHeaders are the SOAP headers decoded from the response.
+	ResponseHeaders *ResponseHeaders
+}
+
+// Invoker performs the round trip described by info.
+type Invoker func(ctx context.Context, info *CallInfo) error
+
+// Interceptor is called around every round trip made by a SOAPClient,
+// including retries. It must call next to send the request, and can
+// inspect info and the returned error afterwards. Returning without calling
+// next short-circuits the request, e.g. to serve a cached response or
+// inject a fault.
+type Interceptor func(ctx context.Context, info *CallInfo, next Invoker) error
+
+func chainInterceptors(interceptors []Interceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(ctx context.Context, info *CallInfo) error {
+			return interceptor(ctx, info, next)
+		}
+	}
+	return invoker
+}
+
+// operationName returns the SOAP operation of a request, which is the local
+// name of its XMLName, e.g. "query" for *Query.
+func operationName(request interface{}) string {
+	t := reflect.TypeOf(request)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Struct {
+		if f, ok := t.FieldByName("XMLName"); ok {
+			tag := strings.Split(f.Tag.Get("xml"), ",")[0]
+			if i := strings.LastIndex(tag, " "); i >= 0 {
+				return tag[i+1:]
+			}
+			if tag != "" {
+				return tag
+			}
+		}
+	}
+	return t.Name()
+}
@@ -0,0 +1,132 @@
+package soapforce
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+)
+
+const (
+	jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// Audiences of the JWT bearer flow.
+	ProductionAudience = "https://login.salesforce.com"
+	SandboxAudience    = "https://test.salesforce.com"
+)
+
+// JWTConfig holds the settings of the OAuth JWT bearer flow, where a
+// connected app with a certificate logs in as Username without a password.
+type JWTConfig struct {
+	// ClientID is the consumer key of the connected app. The ClientID of
+	// the client is used if empty.
+	ClientID string
+	Username string
+	// Audience is ProductionAudience, SandboxAudience or the URL of an
+	// Experience Cloud site. It defaults to the login URL of the client.
+	Audience string
+	// Key is the RSA private key of the certificate uploaded to the
+	// connected app, see ParsePrivateKey.
+	Key crypto.Signer
+	// Expiry is how long the assertion is valid, 3 minutes by default.
+	Expiry time.Duration
+}
+
+// LoginWithJWT logs in with the OAuth JWT bearer flow.
+func (c *Client) LoginWithJWT(config *JWTConfig) (*OAuthToken, error) {
+	return c.LoginWithJWTContext(context.Background(), config)
+}
+
+func (c *Client) LoginWithJWTContext(ctx context.Context, config *JWTConfig) (*OAuthToken, error) {
+	settings := c.settings()
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = settings.ClientID
+	}
+	audience := config.Audience
+	if audience == "" {
+		audience = loginBaseUrl(settings.LoginUrl)
+	}
+	expiry := config.Expiry
+	if expiry <= 0 {
+		expiry = 3 * time.Minute
+	}
+	assertion, err := signJWT(config.Key, map[string]interface{}{
+		"iss": clientID,
+		"sub": config.Username,
+		"aud": audience,
+		"exp": time.Now().Add(expiry).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Add("grant_type", jwtBearerGrantType)
+	params.Add("assertion", assertion)
+	return c.requestToken(ctx, params)
+}
+
+// signJWT returns claims signed with RS256 in the JWS compact serialization.
+func signJWT(key crypto.Signer, claims map[string]interface{}) (string, error) {
+	if key == nil {
+		return "", errors.New("soapforce: no private key to sign the JWT")
+	}
+	if _, ok := key.Public().(*rsa.PublicKey); !ok {
+		return "", fmt.Errorf("soapforce: JWT key must be an RSA key, got %T", key.Public())
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// ParsePrivateKey parses a PEM encoded RSA private key, in PKCS #1 or
+// PKCS #8 form.
+func ParsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("soapforce: no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("soapforce: parsing private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("soapforce: private key must be an RSA key, got %T", key)
+	}
+	return rsaKey, nil
+}
+
+// ReadPrivateKeyFile reads a PEM file, see ParsePrivateKey.
+func ReadPrivateKeyFile(path string) (crypto.Signer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(b)
+}
@@ -0,0 +1,128 @@
+package soapforce
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginWithJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/oauth2/token" {
+			t.Errorf("request to %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if g := r.PostForm.Get("grant_type"); g != jwtBearerGrantType {
+			t.Errorf("grant_type = %q", g)
+		}
+		claims, err := verifyRS256(&key.PublicKey, r.PostForm.Get("assertion"))
+		if err != nil {
+			t.Errorf("assertion: %v", err)
+		}
+		if claims["iss"] != "consumer-key" || claims["sub"] != "user@example.com" || claims["aud"] != server.URL {
+			t.Errorf("claims = %v", claims)
+		}
+		exp, _ := claims["exp"].(float64)
+		if d := time.Until(time.Unix(int64(exp), 0)); d <= 0 || d > 3*time.Minute {
+			t.Errorf("exp is %v from now, want at most 3 minutes", d)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"00Dxx!token","instance_url":"https://example.my.salesforce.com","id":"%s/id/00Dxx/005xx","issued_at":"1700000000000","token_type":"Bearer"}`, server.URL)
+	}))
+	defer server.Close()
+
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	c.SetClientId("consumer-key")
+	token, err := c.LoginWithJWT(&JWTConfig{Username: "user@example.com", Key: signer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "00Dxx!token" {
+		t.Errorf("AccessToken = %q", token.AccessToken)
+	}
+	if got, want := c.soapClient.client.GetServerUrl(), "https://example.my.salesforce.com/services/Soap/u/"+DefaultApiVersion; got != want {
+		t.Errorf("server URL = %q, want %q", got, want)
+	}
+	if c.settings().SessionId != "00Dxx!token" {
+		t.Errorf("SessionId = %q", c.settings().SessionId)
+	}
+}
+
+func TestLoginWithJWTError(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_grant","error_description":"user hasn't approved this consumer"}`)
+	}))
+	defer server.Close()
+
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	_, err = c.LoginWithJWT(&JWTConfig{ClientID: "consumer-key", Username: "user@example.com", Key: key})
+	oauthErr, ok := err.(*OAuthError)
+	if !ok || oauthErr.Code != "invalid_grant" || oauthErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got %v, want an invalid_grant OAuthError", err)
+	}
+}
+
+// verifyRS256 checks the signature of a JWT and returns its claims.
+func verifyRS256(key *rsa.PublicKey, jwt string) (map[string]interface{}, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%d parts", len(parts))
+	}
+	var header map[string]string
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header["alg"] != "RS256" {
+		return nil, fmt.Errorf("alg %q", header["alg"])
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	err = decodeJWTPart(parts[1], &claims)
+	return claims, err
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
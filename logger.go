@@ -0,0 +1,181 @@
+package soapforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+func (l LogLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// defaultSensitiveFields are the elements whose content is always redacted:
+// session ids in SessionHeader and LoginResult, and the passwords sent by
+// Login, SetPassword and the WS-Security header. Names match regardless of
+// case, e.g. password also matches wsse:Password.
+var defaultSensitiveFields = []string{"sessionId", "password"}
+
+// redacted replaces the content of sensitive elements.
+const redacted = "***"
+
+// LogEntry is one structured log record, written for every round trip.
+type LogEntry struct {
+	Time         time.Time     `json:"time"`
+	Level        LogLevel      `json:"level"`
+	Operation    string        `json:"operation"`
+	Duration     time.Duration `json:"duration_ns"`
+	RequestSize  int           `json:"request_bytes"`
+	ResponseSize int           `json:"response_bytes"`
+	StatusCode   int           `json:"status,omitempty"`
+	FaultCode    string        `json:"fault_code,omitempty"`
+	Error        string        `json:"error,omitempty"`
+
+	// The envelopes are only set at LevelDebug, with sensitive fields
+	// redacted.
+	RequestEnvelope  string `json:"request,omitempty"`
+	ResponseEnvelope string `json:"response,omitempty"`
+}
+
+// Logger writes a structured, redacted record of every SOAP round trip.
+// Successful calls are logged at LevelInfo, SOAP faults at LevelWarn and
+// any other failure at LevelError. At LevelDebug the request and response
+// envelopes are included.
+type Logger struct {
+	// Level is the minimum level written.
+	Level LogLevel
+
+	// Output receives one JSON object per line. It defaults to os.Stderr.
+	Output io.Writer
+
+	// Handler, if set, receives the entries instead of Output.
+	Handler func(entry *LogEntry)
+
+	// SensitiveFields are element names, e.g. custom field names, whose
+	// content is redacted in addition to session ids and passwords.
+	SensitiveFields []string
+
+	mu sync.Mutex
+}
+
+// Interceptor returns the interceptor that writes l's records.
+func (l *Logger) Interceptor() Interceptor {
+	redactor := newRedactor(l.SensitiveFields)
+	return func(ctx context.Context, info *CallInfo, next Invoker) error {
+		start := time.Now()
+		err := next(ctx, info)
+		entry := &LogEntry{
+			Time:         start,
+			Level:        LevelInfo,
+			Operation:    info.Operation,
+			Duration:     time.Since(start),
+			RequestSize:  len(info.RequestEnvelope),
+			ResponseSize: len(info.ResponseEnvelope),
+			StatusCode:   info.StatusCode,
+		}
+		if err != nil {
+			entry.Level = LevelError
+			entry.Error = redactor.redactString(err.Error())
+			if code := faultCode(err); code != "" {
+				entry.Level = LevelWarn
+				entry.FaultCode = code
+			}
+		}
+		if entry.Level < l.Level {
+			return err
+		}
+		if l.Level <= LevelDebug {
+			entry.RequestEnvelope = string(redactor.redact(info.RequestEnvelope))
+			entry.ResponseEnvelope = string(redactor.redact(info.ResponseEnvelope))
+		}
+		l.write(entry)
+		return err
+	}
+}
+
+func (l *Logger) write(entry *LogEntry) {
+	if l.Handler != nil {
+		l.Handler(entry)
+		return
+	}
+	out := l.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(entry); err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out.Write(buf.Bytes())
+}
+
+func faultCode(err error) string {
+	var apiFault *ApiFault
+	if errors.As(err, &apiFault) && apiFault.ExceptionCode != nil {
+		return string(*apiFault.ExceptionCode)
+	}
+	var fault *SOAPFault
+	if errors.As(err, &fault) {
+		return strings.TrimPrefix(fault.Code, "sf:")
+	}
+	return ""
+}
+
+type redactor struct {
+	pattern *regexp.Regexp
+}
+
+var defaultRedactor = newRedactor(nil)
+
+func newRedactor(fields []string) *redactor {
+	names := make([]string, 0, len(defaultSensitiveFields)+len(fields))
+	for _, f := range append(append([]string{}, defaultSensitiveFields...), fields...) {
+		names = append(names, regexp.QuoteMeta(f))
+	}
+	// Matches the content of <name>, <prefix:name> and <name attr="...">
+	// up to the next tag, whatever the case of name.
+	pattern := regexp.MustCompile(`(?i)(<(?:[\w.-]+:)?(?:` + strings.Join(names, "|") + `)(?:\s[^>]*)?>)[^<]*`)
+	return &redactor{pattern: pattern}
+}
+
+func (r *redactor) redact(b []byte) []byte {
+	return r.pattern.ReplaceAll(b, []byte("${1}"+redacted))
+}
+
+func (r *redactor) redactString(s string) string {
+	return r.pattern.ReplaceAllString(s, "${1}"+redacted)
+}
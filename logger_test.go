@@ -0,0 +1,77 @@
+package soapforce
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const invalidLoginFaultXML = `<soapenv:Fault><faultcode>sf:INVALID_LOGIN</faultcode>` +
+	`<faultstring>INVALID_LOGIN: Invalid username, password, security token; or user locked out.</faultstring></soapenv:Fault>`
+
+// TestLoginEnvelopeRedacted checks that neither the debug output nor the
+// structured log contain the password of a login or of the WS-Security
+// header.
+func TestLoginEnvelopeRedacted(t *testing.T) {
+	var envelope string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, err := requestBody(r)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		envelope = request
+		writeEnvelope(w, invalidLoginFaultXML)
+	}))
+	defer server.Close()
+
+	debug := new(bytes.Buffer)
+	var entries []*LogEntry
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	c.SetDebug(true)
+	c.SetLogger(debug)
+	c.SetStructuredLogger(&Logger{Level: LevelDebug, Handler: func(entry *LogEntry) {
+		entries = append(entries, entry)
+	}})
+	c.soapClient.AddHeader(&WSSSecurityHeader{
+		XmlNSWsse: WssNsWSSE,
+		Token: &WSSUsernameToken{
+			XmlNSWsu:  WssNsWSU,
+			XmlNSWsse: WssNsWSSE,
+			Username:  &WSSUsername{XmlNSWsse: WssNsWSSE, Data: "user@example.com"},
+			Password:  &WSSPassword{XmlNSWsse: WssNsWSSE, XmlNSType: WssNsType, Data: "wss-secret"},
+		},
+	})
+	if _, err := c.Login("user@example.com", "login-secret"); err == nil {
+		t.Fatal("Login succeeded, want INVALID_LOGIN")
+	}
+
+	for _, secret := range []string{"login-secret", "wss-secret"} {
+		if !strings.Contains(envelope, secret) {
+			t.Fatalf("request does not contain %q: %s", secret, envelope)
+		}
+		if strings.Contains(debug.String(), secret) {
+			t.Errorf("debug output contains %q: %s", secret, debug)
+		}
+		if len(entries) != 1 || strings.Contains(entries[0].RequestEnvelope, secret) {
+			t.Errorf("structured log contains %q: %+v", secret, entries)
+		}
+	}
+	if !strings.Contains(debug.String(), "user@example.com") {
+		t.Errorf("debug output lost the username: %s", debug)
+	}
+}
+
+func TestRedactorIgnoresCase(t *testing.T) {
+	r := newRedactor([]string{"SSN__c"})
+	got := r.redactString(`<sessionId>a</sessionId><sf:ssn__c xsi:type="xsd:string">b</sf:ssn__c>` +
+		`<wsse:Password Type="PasswordText">c</wsse:Password><passwordExpired>false</passwordExpired>`)
+	want := `<sessionId>***</sessionId><sf:ssn__c xsi:type="xsd:string">***</sf:ssn__c>` +
+		`<wsse:Password Type="PasswordText">***</wsse:Password><passwordExpired>false</passwordExpired>`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
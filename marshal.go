@@ -0,0 +1,678 @@
+package soapforce
+
+import (
+	"context"
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SObjectTyper is implemented by structs whose SObject type differs from
+// their Go type name.
+type SObjectTyper interface {
+	SObjectType() string
+}
+
+// Marshal converts a struct into an SObject. Fields are mapped by their sf
+// tag, or by their Go name if it has none:
+//
+//	type Contact struct {
+//		Id          string
+//		LastName    string     `sf:"LastName"`
+//		Email       *string    `sf:",omitempty"`
+//		Birthdate   *Date      // nil is sent in fieldsToNull
+//		CreatedDate time.Time  `sf:",readonly"`
+//		AccountName string     `sf:"Account.Name"`
+//		Account     *Account   `sf:"Account"`
+//		Owner       *User      `sf:"Owner,ref"`
+//		Cases       []*Case    `sf:"Cases"`
+//		Ignored     string     `sf:"-"`
+//		Nulls       []string   `sf:",fieldsToNull"`
+//	}
+//
+// Fields tagged readonly, dotted paths such as Account.Name, parent structs
+// and child relationship slices are only read by Unmarshal. A parent struct
+// tagged ref is sent as a relationship reference instead, to set the parent
+// by external id: its only non-empty field other than Id must be the
+// external id. A nil pointer is sent as null unless the field is omitempty.
+// The names in a []string tagged fieldsToNull are sent as null too, e.g. to
+// clear omitempty fields.
+func Marshal(v interface{}) (*SObject, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, errors.New("soapforce: Marshal(nil)")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("soapforce: Marshal(%T): not a struct", v)
+	}
+	return marshalStruct(rv)
+}
+
+func marshalStruct(rv reflect.Value) (*SObject, error) {
+	s := &SObject{Type: sobjectType(rv), Fields: map[string]interface{}{}}
+	for _, f := range cachedFields(rv.Type()) {
+		if f.readOnly || f.kind == childrenField {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		if f.kind == nullsField {
+			for i := 0; i < fv.Len(); i++ {
+				s.FieldsToNull = append(s.FieldsToNull, fv.Index(i).String())
+			}
+			continue
+		}
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		if f.kind == parentField {
+			if !f.ref {
+				continue
+			}
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			ref, err := marshalRef(fv)
+			if err != nil {
+				return nil, fmt.Errorf("soapforce: field %s: %v", f.name, err)
+			}
+			s.Fields[f.name] = ref
+			continue
+		}
+		var value interface{}
+		if fv.Kind() == reflect.Ptr {
+			if !fv.IsNil() {
+				value = fv.Elem().Interface()
+			}
+		} else {
+			value = fv.Interface()
+		}
+		if f.name == "Id" {
+			if value != nil {
+				id, err := formatFieldValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("soapforce: field Id: %v", err)
+				}
+				s.Id = id
+			}
+			continue
+		}
+		if value == nil {
+			s.FieldsToNull = append(s.FieldsToNull, f.name)
+			continue
+		}
+		s.Fields[f.name] = value
+	}
+	return s, nil
+}
+
+// marshalRef converts a parent struct into a relationship reference, which
+// may only carry the external id of the parent.
+func marshalRef(rv reflect.Value) (*SObject, error) {
+	parent, err := marshalStruct(rv)
+	if err != nil {
+		return nil, err
+	}
+	ref := &SObject{Type: parent.Type, Fields: map[string]interface{}{}}
+	for name, v := range parent.Fields {
+		if _, nested := v.(*SObject); nested || reflect.ValueOf(v).IsZero() {
+			continue
+		}
+		ref.Fields[name] = v
+	}
+	if len(ref.Fields) != 1 {
+		return nil, fmt.Errorf("a reference needs exactly one external id field, %s has %d", ref.Type, len(ref.Fields))
+	}
+	return ref, nil
+}
+
+func sobjectType(rv reflect.Value) string {
+	if t, ok := rv.Interface().(SObjectTyper); ok {
+		return t.SObjectType()
+	}
+	if rv.CanAddr() {
+		if t, ok := rv.Addr().Interface().(SObjectTyper); ok {
+			return t.SObjectType()
+		}
+	}
+	if t, ok := reflect.New(rv.Type()).Interface().(SObjectTyper); ok {
+		return t.SObjectType()
+	}
+	return rv.Type().Name()
+}
+
+// Unmarshal copies the fields of s into the struct pointed to by v, using
+// the same mapping as Marshal. String values are parsed into the type of the
+// struct field, so it works with and without TypedFields. Null fields set
+// pointers to nil.
+func Unmarshal(s *SObject, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("soapforce: Unmarshal(%T): not a pointer to a struct", v)
+	}
+	return unmarshalStruct(s, rv.Elem())
+}
+
+func unmarshalStruct(s *SObject, rv reflect.Value) error {
+	for _, f := range cachedFields(rv.Type()) {
+		if f.kind == nullsField {
+			continue
+		}
+		src, ok := lookupField(s, f.path)
+		if !ok {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		var err error
+		switch f.kind {
+		case parentField:
+			err = unmarshalParent(src, fv)
+		case childrenField:
+			err = unmarshalChildren(src, fv)
+		default:
+			err = assignValue(fv, src)
+		}
+		if err != nil {
+			return fmt.Errorf("soapforce: field %s: %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalAll unmarshals records into the slice pointed to by v, whose
+// elements are structs or pointers to structs.
+func UnmarshalAll(records []*SObject, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("soapforce: UnmarshalAll(%T): not a pointer to a slice", v)
+	}
+	return appendRecords(rv.Elem(), records)
+}
+
+func appendRecords(slice reflect.Value, records []*SObject) error {
+	elemType := slice.Type().Elem()
+	ptr := elemType.Kind() == reflect.Ptr
+	if ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal records into %s", slice.Type())
+	}
+	for _, r := range records {
+		elem := reflect.New(elemType)
+		if err := unmarshalStruct(r, elem.Elem()); err != nil {
+			return err
+		}
+		if ptr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+	return nil
+}
+
+func unmarshalParent(src interface{}, fv reflect.Value) error {
+	parent, ok := src.(*SObject)
+	if !ok || parent == nil {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := unmarshalStruct(parent, elem.Elem()); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+	return unmarshalStruct(parent, fv)
+}
+
+func unmarshalChildren(src interface{}, fv reflect.Value) error {
+	fv.Set(reflect.Zero(fv.Type()))
+	children, ok := src.(*QueryResult)
+	if !ok || children == nil {
+		return nil
+	}
+	return appendRecords(fv, children.Records)
+}
+
+// lookupField follows a relationship path such as Account.Owner.Name. Null
+// fields are nil, whether or not their types were converted.
+func lookupField(s *SObject, path []string) (interface{}, bool) {
+	for i, name := range path {
+		if s == nil {
+			return nil, true
+		}
+		var v interface{}
+		var ok bool
+		switch name {
+		case "Id":
+			v, ok = s.Id, true
+		case "type":
+			v, ok = s.Type, true
+		default:
+			v, ok = s.Fields[name]
+			if s.decodedNull(name) {
+				v = nil
+			}
+		}
+		if !ok || i == len(path)-1 {
+			return v, ok
+		}
+		next, isSObject := v.(*SObject)
+		if !isSObject {
+			// a null parent
+			return nil, true
+		}
+		s = next
+	}
+	return nil, false
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	dateType            = reflect.TypeOf(Date{})
+	addressType         = reflect.TypeOf(Address{})
+	locationType        = reflect.TypeOf(Location{})
+	sobjectStructType   = reflect.TypeOf(SObject{})
+	byteSliceType       = reflect.TypeOf([]byte(nil))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// assignValue stores a decoded field value, either a string or a value
+// decoded by ConvertFieldTypes, in dst.
+func assignValue(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if text, ok := src.(string); ok && text == "" && dst.Type().Elem().Kind() != reflect.String {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		sv := reflect.ValueOf(src)
+		if sv.Type().AssignableTo(dst.Type()) {
+			dst.Set(sv)
+			return nil
+		}
+		elem := reflect.New(dst.Type().Elem())
+		if err := assignValue(elem.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	if sv.Kind() == reflect.Ptr {
+		if sv.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if sv.Elem().Type().AssignableTo(dst.Type()) {
+			dst.Set(sv.Elem())
+			return nil
+		}
+	}
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+
+	if text, ok := src.(string); ok {
+		return parseValue(dst, text)
+	}
+	switch src := src.(type) {
+	case time.Time:
+		if dst.Type() == dateType {
+			dst.Set(reflect.ValueOf(Date{src}))
+			return nil
+		}
+	case Date:
+		if dst.Type() == timeType {
+			dst.Set(reflect.ValueOf(src.Time))
+			return nil
+		}
+	}
+	if isNumber(sv.Kind()) && isNumber(dst.Kind()) {
+		dst.Set(sv.Convert(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.String {
+		text, err := formatFieldValue(src)
+		if err != nil {
+			return err
+		}
+		dst.SetString(text)
+		return nil
+	}
+	return fmt.Errorf("cannot unmarshal %T into %s", src, dst.Type())
+}
+
+// parseValue parses the text of a field into dst.
+func parseValue(dst reflect.Value, text string) error {
+	switch dst.Type() {
+	case timeType:
+		if text == "" {
+			dst.Set(reflect.Zero(timeType))
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			t, err = time.Parse(dateLayout, text)
+		}
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	case byteSliceType:
+		b, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return err
+		}
+		dst.SetBytes(b)
+		return nil
+	}
+	if text != "" && reflect.PtrTo(dst.Type()).Implements(textUnmarshalerType) {
+		return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(text)
+		return nil
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.String {
+			// multi-select picklist
+			dst.Set(reflect.Zero(dst.Type()))
+			if text == "" {
+				return nil
+			}
+			for _, item := range strings.Split(text, ";") {
+				dst.Set(reflect.Append(dst, reflect.ValueOf(item).Convert(dst.Type().Elem())))
+			}
+			return nil
+		}
+	}
+	if text == "" {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, dst.Type().Bits())
+		if err != nil {
+			// numbers are sent as doubles, e.g. 5.0
+			f, ferr := strconv.ParseFloat(text, 64)
+			if ferr != nil || f != float64(int64(f)) {
+				return err
+			}
+			n = int64(f)
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, dst.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+	return fmt.Errorf("cannot unmarshal %q into %s", text, dst.Type())
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+type fieldKind int
+
+const (
+	valueField fieldKind = iota
+	parentField
+	childrenField
+	// nullsField is a []string of field names to send in fieldsToNull.
+	nullsField
+)
+
+type structField struct {
+	name      string
+	path      []string
+	index     []int
+	kind      fieldKind
+	omitEmpty bool
+	readOnly  bool
+	// ref marks a parent struct sent as a relationship reference.
+	ref bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]structField
+
+func cachedFields(t reflect.Type) []structField {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t, nil))
+	return f.([]structField)
+}
+
+func typeFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("sf")
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		if sf.Anonymous && !hasTag && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, typeFields(sf.Type, fieldIndex)...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		f := structField{name: parts[0], index: fieldIndex, kind: kindOf(sf.Type)}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "readonly":
+				f.readOnly = true
+			case "ref":
+				f.ref = true
+			case "fieldsToNull":
+				if sf.Type.Kind() == reflect.Slice && sf.Type.Elem().Kind() == reflect.String {
+					f.kind = nullsField
+				}
+			}
+		}
+		f.path = strings.Split(f.name, ".")
+		if len(f.path) > 1 {
+			f.readOnly = true
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func kindOf(t reflect.Type) fieldKind {
+	if t.Kind() == reflect.Slice && t != byteSliceType {
+		elem := t.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if isRecordStruct(elem) {
+			return childrenField
+		}
+		return valueField
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isRecordStruct(t) {
+		return parentField
+	}
+	return valueField
+}
+
+// isRecordStruct reports whether t is a struct mapped to a record rather
+// than a single field value.
+func isRecordStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	switch t {
+	case timeType, dateType, addressType, locationType, sobjectStructType:
+		return false
+	}
+	return !t.Implements(textMarshalerType) && !reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// marshalAll marshals a slice of structs, or a single struct.
+func marshalAll(v interface{}) ([]*SObject, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		s, err := Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return []*SObject{s}, nil
+	}
+	sobjects := make([]*SObject, rv.Len())
+	for i := range sobjects {
+		s, err := Marshal(rv.Index(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("soapforce: record %d: %v", i, err)
+		}
+		sobjects[i] = s
+	}
+	return sobjects, nil
+}
+
+// setCreatedIds copies the ids of created records into the Id field of the
+// structs they were marshalled from, if they are addressable.
+func setCreatedIds(v interface{}, results []*SaveResult) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		rv = reflect.ValueOf([]interface{}{v})
+	}
+	for i := 0; i < rv.Len() && i < len(results); i++ {
+		if results[i] == nil || !results[i].Success || results[i].Id == "" {
+			continue
+		}
+		elem := rv.Index(i)
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			if elem.IsNil() {
+				break
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct || !elem.CanAddr() {
+			continue
+		}
+		for _, f := range cachedFields(elem.Type()) {
+			if f.name == "Id" {
+				assignValue(elem.FieldByIndex(f.index), results[i].Id)
+			}
+		}
+	}
+}
+
+// CreateStructs marshals v, a struct or a slice of structs, and creates the
+// records. The new ids are stored in the Id fields of pointers to structs.
+func (c *Client) CreateStructs(v interface{}, opts ...CallOption) ([]*SaveResult, error) {
+	return c.CreateStructsContext(context.Background(), v, opts...)
+}
+
+func (c *Client) CreateStructsContext(ctx context.Context, v interface{}, opts ...CallOption) ([]*SaveResult, error) {
+	sobjects, err := marshalAll(v)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.CreateContext(ctx, sobjects, opts...)
+	setCreatedIds(v, res)
+	return res, err
+}
+
+// UpdateStructs marshals v, a struct or a slice of structs, and updates the
+// records.
+func (c *Client) UpdateStructs(v interface{}, opts ...CallOption) ([]*SaveResult, error) {
+	return c.UpdateStructsContext(context.Background(), v, opts...)
+}
+
+func (c *Client) UpdateStructsContext(ctx context.Context, v interface{}, opts ...CallOption) ([]*SaveResult, error) {
+	sobjects, err := marshalAll(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.UpdateContext(ctx, sobjects, opts...)
+}
+
+// QueryStructs runs q, fetching every batch, and unmarshals the records
+// into the slice pointed to by v.
+func (c *Client) QueryStructs(q string, v interface{}, opts ...CallOption) error {
+	return c.QueryStructsContext(context.Background(), q, v, opts...)
+}
+
+func (c *Client) QueryStructsContext(ctx context.Context, q string, v interface{}, opts ...CallOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("soapforce: QueryStructs(%T): not a pointer to a slice", v)
+	}
+	rv.Elem().Set(reflect.MakeSlice(rv.Elem().Type(), 0, 0))
+	res, err := c.QueryContext(ctx, q, opts...)
+	for {
+		if err != nil {
+			return err
+		}
+		if err := UnmarshalAll(res.Records, v); err != nil {
+			return err
+		}
+		if res.Done || res.QueryLocator == "" {
+			return nil
+		}
+		res, err = c.QueryMoreContext(ctx, res.QueryLocator, opts...)
+	}
+}
@@ -0,0 +1,172 @@
+package soapforce
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const contactRecordXML = `<records xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:sf="urn:sobject.partner.soap.sforce.com" xsi:type="sf:sObject">` +
+	`<sf:type>Contact</sf:type>` +
+	`<sf:Id>003000000000001AAA</sf:Id>` +
+	`<sf:LastName xsi:type="xsd:string">Smith</sf:LastName>` +
+	`<sf:Title xsi:type="xsd:string">CEO</sf:Title>` +
+	`<sf:Phone xsi:nil="true"/>` +
+	`<sf:Description xsi:nil="true"/>` +
+	`<sf:Age__c xsi:type="xsd:double">42.0</sf:Age__c>` +
+	`<sf:Score__c xsi:nil="true"/>` +
+	`<sf:Account xsi:nil="true"/>` +
+	`</records>`
+
+type unmarshalAccount struct {
+	Name string
+}
+
+type unmarshalContact struct {
+	Id          string
+	LastName    string
+	Title       *string
+	Phone       *string
+	Description string
+	Age         int      `sf:"Age__c"`
+	Score       *float64 `sf:"Score__c"`
+	AccountName *string  `sf:"Account.Name"`
+	Account     *unmarshalAccount
+}
+
+func TestUnmarshalNullFields(t *testing.T) {
+	for _, typed := range []bool{false, true} {
+		var s SObject
+		if err := xml.Unmarshal([]byte(contactRecordXML), &s); err != nil {
+			t.Fatal(err)
+		}
+		if typed {
+			if err := s.ConvertFieldTypes(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c := unmarshalContact{Phone: new(string), Score: new(float64), Account: &unmarshalAccount{}}
+		if err := Unmarshal(&s, &c); err != nil {
+			t.Fatalf("typed=%v: %v", typed, err)
+		}
+		if c.Id != "003000000000001AAA" || c.LastName != "Smith" || c.Age != 42 {
+			t.Errorf("typed=%v: got Id=%q LastName=%q Age=%d", typed, c.Id, c.LastName, c.Age)
+		}
+		if c.Title == nil || *c.Title != "CEO" {
+			t.Errorf("typed=%v: Title = %v, want CEO", typed, c.Title)
+		}
+		if c.Phone != nil {
+			t.Errorf("typed=%v: Phone = %q, want nil", typed, *c.Phone)
+		}
+		if c.Description != "" {
+			t.Errorf("typed=%v: Description = %q, want empty", typed, c.Description)
+		}
+		if c.Score != nil {
+			t.Errorf("typed=%v: Score = %v, want nil", typed, *c.Score)
+		}
+		if c.AccountName != nil || c.Account != nil {
+			t.Errorf("typed=%v: AccountName = %v, Account = %v, want nil", typed, c.AccountName, c.Account)
+		}
+	}
+}
+
+func TestUnmarshalChangedNullField(t *testing.T) {
+	var s SObject
+	if err := xml.Unmarshal([]byte(contactRecordXML), &s); err != nil {
+		t.Fatal(err)
+	}
+	s.Fields["Phone"] = "555-0100"
+	var c unmarshalContact
+	if err := Unmarshal(&s, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Phone == nil || *c.Phone != "555-0100" {
+		t.Errorf("Phone = %v, want 555-0100", c.Phone)
+	}
+}
+
+type roundTripAccount struct {
+	Name string
+}
+
+type roundTripUser struct {
+	FederationIdentifier string
+}
+
+func (roundTripUser) SObjectType() string { return "User" }
+
+type roundTripContact struct {
+	Id        string
+	LastName  string
+	AccountId string
+	Account   *roundTripAccount
+	Owner     *roundTripUser `sf:",ref,omitempty"`
+}
+
+func (roundTripContact) SObjectType() string { return "Contact" }
+
+// TestQueryUpdateStructsRoundTrip checks that a struct read with its parent
+// can be updated: the parent is not sent along with AccountId.
+func TestQueryUpdateStructsRoundTrip(t *testing.T) {
+	var update string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, err := requestBody(r)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		if strings.Contains(request, "<query") {
+			writeEnvelope(w, `<queryResponse><result><done>true</done><queryLocator xsi:nil="true"/>`+
+				`<records xsi:type="sf:sObject"><sf:type>Contact</sf:type><sf:Id>003000000000001AAA</sf:Id>`+
+				`<sf:LastName>Smith</sf:LastName><sf:AccountId>001000000000001AAA</sf:AccountId>`+
+				`<sf:Account xsi:type="sf:sObject"><sf:type>Account</sf:type><sf:Id xsi:nil="true"/><sf:Name>Acme</sf:Name></sf:Account>`+
+				`</records><size>1</size></result></queryResponse>`)
+			return
+		}
+		update = request
+		writeEnvelope(w, "<updateResponse>"+saveResultXML+"</updateResponse>")
+	}))
+	defer server.Close()
+
+	c := NewClient()
+	c.SetServerUrl(server.URL)
+	c.SetLogger(ioutil.Discard)
+	c.SetAccessToken("sid")
+	var contacts []*roundTripContact
+	if err := c.QueryStructs("SELECT Id, LastName, AccountId, Account.Name FROM Contact", &contacts); err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 1 || contacts[0].Account == nil || contacts[0].Account.Name != "Acme" {
+		t.Fatalf("got %+v, want the contact with its account", contacts)
+	}
+	contacts[0].LastName = "Jones"
+	if _, err := c.UpdateStructs(contacts); err != nil {
+		t.Fatal(err)
+	}
+	want := `<sObjects xmlns="urn:sobject.partner.soap.sforce.com"><type>Contact</type><Id>003000000000001AAA</Id>` +
+		`<AccountId>001000000000001AAA</AccountId><LastName>Jones</LastName></sObjects>`
+	if !strings.Contains(update, want) {
+		t.Errorf("update request is %s, want %s", update, want)
+	}
+}
+
+func TestMarshalRef(t *testing.T) {
+	s, err := Marshal(&roundTripContact{LastName: "Smith", Owner: &roundTripUser{FederationIdentifier: "jdoe"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, ok := s.Fields["Owner"].(*SObject)
+	if !ok || ref.Type != "User" || len(ref.Fields) != 1 || ref.Fields["FederationIdentifier"] != "jdoe" {
+		t.Errorf("Owner = %#v, want a reference by FederationIdentifier", s.Fields["Owner"])
+	}
+	if _, ok := s.Fields["Account"]; ok {
+		t.Error("Account was marshalled without the ref option")
+	}
+
+	if _, err := Marshal(&roundTripContact{Owner: &roundTripUser{}}); err == nil {
+		t.Error("Marshal succeeded with an empty reference")
+	}
+}
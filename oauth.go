@@ -0,0 +1,338 @@
+package soapforce
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OAuthToken is the response of the OAuth token endpoint.
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	// RefreshToken is only returned when the refresh_token scope was
+	// granted. Refresh sets it to the refresh token to use next.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	InstanceUrl  string `json:"instance_url"`
+	// Id is the identity URL of the user.
+	Id string `json:"id"`
+	// IssuedAt is in milliseconds since the epoch, see IssuedTime.
+	IssuedAt string `json:"issued_at"`
+	// Signature is the HMAC-SHA256 of Id and IssuedAt keyed with the client
+	// secret, see Verify.
+	Signature string `json:"signature,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type"`
+
+	// LoginUrl is the login host the token was requested from. It is not
+	// part of the response; it is saved with the token so that a restored
+	// token is only used with the same login host.
+	LoginUrl string `json:"login_url,omitempty"`
+}
+
+// IssuedTime returns IssuedAt as a time, or the zero time if it is not set.
+func (t *OAuthToken) IssuedTime() time.Time {
+	ms, err := strconv.ParseInt(t.IssuedAt, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// ErrInvalidSignature is returned when the signature of a token response
+// is missing or does not match the client secret.
+var ErrInvalidSignature = errors.New("soapforce: invalid OAuth token signature")
+
+// Verify checks that the token was issued for the connected app with
+// clientSecret.
+func (t *OAuthToken) Verify(clientSecret string) error {
+	sig, err := base64.StdEncoding.DecodeString(t.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(clientSecret))
+	mac.Write([]byte(t.Id + t.IssuedAt))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// OAuthError is an error response of the OAuth endpoints, e.g.
+// invalid_grant.
+type OAuthError struct {
+	// StatusCode is the HTTP status, 0 for an error returned to the
+	// redirect URL of the web server flow.
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("soapforce: oauth: %s", e.Code)
+	}
+	return fmt.Sprintf("soapforce: oauth: %s: %s", e.Code, e.Description)
+}
+
+// requestToken posts params to the token endpoint, switches the client to
+// the new session and saves the token in the token store. When params has a
+// client secret, the response must be signed with it. The endpoint's
+// certificate is verified unless the caller set its own HTTP client or
+// transport.
+func (c *Client) requestToken(ctx context.Context, params url.Values) (*OAuthToken, error) {
+	settings := c.settings()
+	endpoint := loginBaseUrl(settings.LoginUrl) + "/services/oauth2/token"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.soapClient.client.getVerifyingHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		oauthErr := &OAuthError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(b, oauthErr) == nil && oauthErr.Code != "" {
+			return nil, oauthErr
+		}
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: b}
+	}
+
+	token := &OAuthToken{}
+	if err := json.Unmarshal(b, token); err != nil {
+		return nil, fmt.Errorf("soapforce: decoding token response: %v", err)
+	}
+	if token.AccessToken == "" || token.InstanceUrl == "" {
+		return nil, errors.New("soapforce: token response has no access_token or instance_url")
+	}
+	if secret := params.Get("client_secret"); secret != "" {
+		if err := token.Verify(secret); err != nil {
+			return nil, err
+		}
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = params.Get("refresh_token")
+	}
+	token.LoginUrl = loginBaseUrl(settings.LoginUrl)
+
+	c.setToken(token)
+	if err := c.saveToken(ctx, token); err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+// sessionUrl returns the SOAP endpoint of an instance.
+func sessionUrl(instanceUrl, apiVersion string) string {
+	return fmt.Sprintf("%s/services/Soap/u/%s", instanceUrl, apiVersion)
+}
+
+// WebServerFlow holds the settings of the OAuth web server flow with PKCE,
+// for tools run by a user: the user logs in with a browser and the
+// authorization code is received by a local listener.
+type WebServerFlow struct {
+	// ClientID is the consumer key of the connected app. The ClientID of
+	// the client is used if empty.
+	ClientID string
+	// ClientSecret is sent if the connected app requires it. The
+	// ClientSecret of the client is used if empty.
+	ClientSecret string
+	// RedirectURL is the callback URL of the connected app, e.g.
+	// http://localhost:1717/OauthRedirect. The listener is started on its
+	// host, which must be localhost or a loopback address, and port.
+	RedirectURL string
+	// Scopes are requested in addition to the ones of the connected app,
+	// e.g. "api" and "refresh_token".
+	Scopes []string
+	// OpenURL is called with the authorize URL, e.g. to open a browser or
+	// print the URL.
+	OpenURL func(authorizeURL string) error
+	// Timeout bounds the wait for the user to log in, 5 minutes by default.
+	Timeout time.Duration
+}
+
+// PKCE is a code verifier and its S256 challenge, see NewPKCE.
+type PKCE struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCE returns a random code verifier.
+func NewPKCE() (*PKCE, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return &PKCE{Verifier: verifier, Challenge: base64.RawURLEncoding.EncodeToString(sum[:])}, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthorizeURL returns the URL of the login page of the web server flow.
+func (c *Client) AuthorizeURL(flow *WebServerFlow, state string, pkce *PKCE) string {
+	settings := c.settings()
+	clientID := flow.ClientID
+	if clientID == "" {
+		clientID = settings.ClientID
+	}
+	params := url.Values{}
+	params.Add("response_type", "code")
+	params.Add("client_id", clientID)
+	params.Add("redirect_uri", flow.RedirectURL)
+	params.Add("state", state)
+	params.Add("code_challenge", pkce.Challenge)
+	params.Add("code_challenge_method", "S256")
+	if len(flow.Scopes) > 0 {
+		params.Add("scope", strings.Join(flow.Scopes, " "))
+	}
+	return loginBaseUrl(settings.LoginUrl) + "/services/oauth2/authorize?" + params.Encode()
+}
+
+// ExchangeCode requests tokens for an authorization code and switches the
+// client to the new session.
+func (c *Client) ExchangeCode(flow *WebServerFlow, code string, pkce *PKCE) (*OAuthToken, error) {
+	return c.ExchangeCodeContext(context.Background(), flow, code, pkce)
+}
+
+func (c *Client) ExchangeCodeContext(ctx context.Context, flow *WebServerFlow, code string, pkce *PKCE) (*OAuthToken, error) {
+	settings := c.settings()
+	clientID, clientSecret := flow.ClientID, flow.ClientSecret
+	if clientID == "" {
+		clientID = settings.ClientID
+	}
+	if clientSecret == "" {
+		clientSecret = settings.ClientSecret
+	}
+	params := url.Values{}
+	params.Add("grant_type", "authorization_code")
+	params.Add("code", code)
+	params.Add("client_id", clientID)
+	if clientSecret != "" {
+		params.Add("client_secret", clientSecret)
+	}
+	params.Add("redirect_uri", flow.RedirectURL)
+	params.Add("code_verifier", pkce.Verifier)
+	return c.requestToken(ctx, params)
+}
+
+// LoginWithWebServerFlow runs the web server flow: it listens on the
+// redirect URL, passes the authorize URL to flow.OpenURL, waits for the
+// user to log in and exchanges the code for tokens. Keep the RefreshToken
+// of the result to call Refresh later.
+func (c *Client) LoginWithWebServerFlow(flow *WebServerFlow) (*OAuthToken, error) {
+	return c.LoginWithWebServerFlowContext(context.Background(), flow)
+}
+
+func (c *Client) LoginWithWebServerFlowContext(ctx context.Context, flow *WebServerFlow) (*OAuthToken, error) {
+	if flow.OpenURL == nil {
+		return nil, errors.New("soapforce: WebServerFlow.OpenURL is not set")
+	}
+	redirect, err := url.Parse(flow.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("soapforce: invalid redirect URL: %v", err)
+	}
+	if redirect.Scheme != "http" || !isLoopback(redirect.Hostname()) {
+		return nil, fmt.Errorf("soapforce: redirect URL must be a local http URL, got %q", flow.RedirectURL)
+	}
+	pkce, err := NewPKCE()
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	timeout := flow.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return nil, err
+	}
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var cb callback
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Invalid state.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			cb.err = &OAuthError{Code: q.Get("error"), Description: q.Get("error_description")}
+			fmt.Fprintln(w, "Login failed, you can close this window.")
+		default:
+			cb.code = q.Get("code")
+			fmt.Fprintln(w, "Login complete, you can close this window.")
+		}
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := flow.OpenURL(c.AuthorizeURL(flow, state, pkce)); err != nil {
+		return nil, err
+	}
+	var cb callback
+	select {
+	case cb = <-callbacks:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if cb.err != nil {
+		return nil, cb.err
+	}
+	return c.ExchangeCodeContext(ctx, flow, cb.code, pkce)
+}
+
+// isLoopback reports whether host is localhost or a loopback address, so
+// that the callback listener is not reachable from other hosts.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
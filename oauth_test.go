@@ -0,0 +1,234 @@
+package soapforce
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTLSTokenServer starts a token endpoint with a self-signed certificate.
+func newTLSTokenServer(requests *int) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"00Dxx!token","instance_url":"https://example.my.salesforce.com","id":"https://login.salesforce.com/id/00Dxx/005xx","issued_at":"1700000000000","token_type":"Bearer"}`)
+	}))
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	return server
+}
+
+func TestExchangeCodeVerifiesTLS(t *testing.T) {
+	var requests int
+	server := newTLSTokenServer(&requests)
+	defer server.Close()
+
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	_, err := c.ExchangeCode(&WebServerFlow{ClientID: "consumer-key"}, "code", &PKCE{Verifier: "verifier"})
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuthority) {
+		t.Fatalf("got %v, want an unknown certificate authority error", err)
+	}
+	if requests != 0 {
+		t.Errorf("token endpoint got %d requests", requests)
+	}
+	if c.Token() != nil {
+		t.Errorf("Token() = %v, want nil", c.Token())
+	}
+}
+
+func TestExchangeCodeWithHTTPClient(t *testing.T) {
+	var requests int
+	server := newTLSTokenServer(&requests)
+	defer server.Close()
+
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	c.SetHTTPClient(server.Client())
+	token, err := c.ExchangeCode(&WebServerFlow{ClientID: "consumer-key"}, "code", &PKCE{Verifier: "verifier"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "00Dxx!token" || requests != 1 {
+		t.Errorf("got token %q after %d requests", token.AccessToken, requests)
+	}
+}
+
+// freeRedirectURL returns a redirect URL on a loopback port that is free.
+func freeRedirectURL(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return "http://" + l.Addr().String() + "/OauthRedirect"
+}
+
+func TestLoginWithWebServerFlow(t *testing.T) {
+	var challenge, redirectURL string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form := r.PostForm
+		if form.Get("grant_type") != "authorization_code" || form.Get("code") != "auth-code" || form.Get("redirect_uri") != redirectURL {
+			t.Errorf("token request %v", form)
+		}
+		sum := sha256.Sum256([]byte(form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			t.Errorf("code_verifier %q does not match the challenge %q", form.Get("code_verifier"), challenge)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"00Dxx!token","refresh_token":"refresh","instance_url":"%s","id":"%s/id/00Dxx/005xx","issued_at":"1700000000000","token_type":"Bearer"}`, server.URL, server.URL)
+	}))
+	defer server.Close()
+
+	redirectURL = freeRedirectURL(t)
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	token, err := c.LoginWithWebServerFlow(&WebServerFlow{
+		ClientID:    "consumer-key",
+		RedirectURL: redirectURL,
+		Timeout:     5 * time.Second,
+		OpenURL: func(authorizeURL string) error {
+			u, err := url.Parse(authorizeURL)
+			if err != nil {
+				return err
+			}
+			q := u.Query()
+			if u.Path != "/services/oauth2/authorize" || q.Get("code_challenge_method") != "S256" || q.Get("redirect_uri") != redirectURL {
+				t.Errorf("authorize URL %s", authorizeURL)
+			}
+			challenge = q.Get("code_challenge")
+			// the browser is redirected after the login
+			resp, err := http.Get(redirectURL + "?code=auth-code&state=" + url.QueryEscape(q.Get("state")))
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("callback status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.RefreshToken != "refresh" || c.Token() != token {
+		t.Errorf("got token %+v", token)
+	}
+}
+
+func TestLoginWithWebServerFlowStateMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("code exchanged after a callback with another state")
+	}))
+	defer server.Close()
+
+	redirectURL := freeRedirectURL(t)
+	c := NewClient()
+	c.SetLoginUrl(server.URL)
+	_, err := c.LoginWithWebServerFlow(&WebServerFlow{
+		ClientID:    "consumer-key",
+		RedirectURL: redirectURL,
+		Timeout:     500 * time.Millisecond,
+		OpenURL: func(string) error {
+			resp, err := http.Get(redirectURL + "?code=forged&state=other")
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("callback status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+			return nil
+		},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the flow to time out", err)
+	}
+}
+
+func TestLoginWithWebServerFlowRedirectHost(t *testing.T) {
+	for _, redirectURL := range []string{
+		"http://0.0.0.0:1717/OauthRedirect",
+		"http://192.0.2.1:1717/OauthRedirect",
+		"http://example.com:1717/OauthRedirect",
+		"http://:1717/OauthRedirect",
+		"https://localhost:1717/OauthRedirect",
+	} {
+		c := NewClient()
+		_, err := c.LoginWithWebServerFlow(&WebServerFlow{
+			RedirectURL: redirectURL,
+			OpenURL: func(string) error {
+				t.Errorf("%s: flow started", redirectURL)
+				return nil
+			},
+		})
+		if err == nil || !strings.Contains(err.Error(), "must be a local http URL") {
+			t.Errorf("%s: got %v, want it rejected", redirectURL, err)
+		}
+	}
+}
+
+func TestTokenSignature(t *testing.T) {
+	const id, issuedAt = "https://login.salesforce.com/id/00Dxx/005xx", "1700000000000"
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(id + issuedAt))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		err       error
+	}{
+		{"signed", "secret", sign("secret"), nil},
+		{"missing", "secret", "", ErrInvalidSignature},
+		{"other secret", "secret", sign("other"), ErrInvalidSignature},
+		{"no secret sent", "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(&OAuthToken{
+					AccessToken: "00Dxx!token",
+					InstanceUrl: "https://example.my.salesforce.com",
+					Id:          id,
+					IssuedAt:    issuedAt,
+					Signature:   tt.signature,
+				})
+			}))
+			defer server.Close()
+
+			c := NewClient()
+			c.SetLoginUrl(server.URL)
+			c.SetClientId("consumer-key")
+			c.SetClientSecret(tt.secret)
+			_, err := c.LoginWithOAuth("user@example.com", "password")
+			if err != tt.err {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if signed := c.Token() != nil; signed != (err == nil) {
+				t.Errorf("Token() = %v after %v", c.Token(), err)
+			}
+		})
+	}
+}
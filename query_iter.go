@@ -0,0 +1,212 @@
+package soapforce
+
+import (
+	"context"
+)
+
+// QueryIterator walks the records of a query, calling QueryMore as each
+// batch is used up. It is not safe for concurrent use.
+//
+//	it := client.QueryIter(ctx, "SELECT Id, Name FROM Account")
+//	defer it.Close()
+//	for it.Next() {
+//		fmt.Println(it.Record().Fields["Name"])
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type QueryIterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *Client
+	query  string
+	all    bool
+
+	readAhead     bool
+	fetchChildren bool
+	pending       chan queryPage
+
+	page   *QueryResult
+	index  int
+	record *SObject
+	count  int
+	size   int
+	err    error
+	closed bool
+}
+
+type queryPage struct {
+	result *QueryResult
+	err    error
+}
+
+// QueryIter returns an iterator over the records of q. No request is made
+// until Next is called. Pass ReadAhead to fetch the next batch while the
+// current one is being read.
+func (c *Client) QueryIter(ctx context.Context, q string, opts ...CallOption) *QueryIterator {
+	return c.newQueryIterator(ctx, q, false, opts)
+}
+
+// QueryAllIter is QueryIter for QueryAll, which includes deleted and
+// archived records.
+func (c *Client) QueryAllIter(ctx context.Context, q string, opts ...CallOption) *QueryIterator {
+	return c.newQueryIterator(ctx, q, true, opts)
+}
+
+func (c *Client) newQueryIterator(ctx context.Context, q string, all bool, opts []CallOption) *QueryIterator {
+	ctx = WithCallOptions(ctx, opts...)
+	o := callOptionsFrom(ctx)
+	// subqueries are fetched in Next, for the records that are reached
+	ctx, cancel := context.WithCancel(WithCallOptions(ctx, withoutFetchChildren))
+	return &QueryIterator{
+		ctx:           ctx,
+		cancel:        cancel,
+		client:        c,
+		query:         q,
+		all:           all,
+		readAhead:     o.readAhead,
+		fetchChildren: o.fetchChildren,
+	}
+}
+
+// Next advances to the next record. It returns false when there are no
+// more records or a call failed, see Err.
+func (it *QueryIterator) Next() bool {
+	if it.err != nil || it.closed {
+		return false
+	}
+	for it.page == nil || it.index >= len(it.page.Records) {
+		if it.page != nil && (it.page.Done || it.page.QueryLocator == "") {
+			it.record = nil
+			return false
+		}
+		page, err := it.fetch()
+		if err != nil {
+			it.err = err
+			it.record = nil
+			return false
+		}
+		if it.page == nil {
+			it.size = int(page.Size)
+		}
+		it.page, it.index = page, 0
+		it.prefetch()
+	}
+	it.record = it.page.Records[it.index]
+	it.index++
+	it.count++
+	if it.fetchChildren {
+		if err := it.client.FetchChildren(it.ctx, it.record); err != nil {
+			it.err = err
+			it.record = nil
+			return false
+		}
+	}
+	return true
+}
+
+func (it *QueryIterator) fetch() (*QueryResult, error) {
+	if it.pending != nil {
+		p := <-it.pending
+		it.pending = nil
+		return p.result, p.err
+	}
+	if it.page == nil {
+		if it.all {
+			return it.client.QueryAllContext(it.ctx, it.query)
+		}
+		return it.client.QueryContext(it.ctx, it.query)
+	}
+	return it.client.QueryMoreContext(it.ctx, it.page.QueryLocator)
+}
+
+// prefetch starts fetching the batch after the current one.
+func (it *QueryIterator) prefetch() {
+	if !it.readAhead || it.page.Done || it.page.QueryLocator == "" {
+		return
+	}
+	pending := make(chan queryPage, 1)
+	go func(ctx context.Context, locator string) {
+		res, err := it.client.QueryMoreContext(ctx, locator)
+		pending <- queryPage{result: res, err: err}
+	}(it.ctx, it.page.QueryLocator)
+	it.pending = pending
+}
+
+// Record returns the current record.
+func (it *QueryIterator) Record() *SObject {
+	return it.record
+}
+
+// Decode unmarshals the current record into v, see Unmarshal.
+func (it *QueryIterator) Decode(v interface{}) error {
+	return Unmarshal(it.record, v)
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *QueryIterator) Err() error {
+	return it.err
+}
+
+// Size returns the total number of records matched by the query, known
+// once Next has been called.
+func (it *QueryIterator) Size() int {
+	return it.size
+}
+
+// Count returns the number of records returned by Next so far.
+func (it *QueryIterator) Count() int {
+	return it.count
+}
+
+// Progress returns the fraction of the records returned so far, from 0 to 1.
+func (it *QueryIterator) Progress() float64 {
+	if it.size == 0 {
+		if it.page != nil && it.page.Done {
+			return 1
+		}
+		return 0
+	}
+	return float64(it.count) / float64(it.size)
+}
+
+// Close stops a batch being read ahead. Next returns false afterwards.
+func (it *QueryIterator) Close() error {
+	it.cancel()
+	it.closed = true
+	it.record = nil
+	return nil
+}
+
+// FetchChildren calls QueryMore on the subquery results of records, e.g.
+// the Contacts of an Account, until they hold every child record.
+func (c *Client) FetchChildren(ctx context.Context, records ...*SObject) error {
+	ctx = WithCallOptions(ctx, withoutFetchChildren)
+	for _, r := range records {
+		if r == nil {
+			continue
+		}
+		for _, v := range r.Fields {
+			children, ok := v.(*QueryResult)
+			if !ok || children == nil {
+				continue
+			}
+			for !children.Done && children.QueryLocator != "" {
+				more, err := c.QueryMoreContext(ctx, children.QueryLocator)
+				if err != nil {
+					return err
+				}
+				children.Records = append(children.Records, more.Records...)
+				children.Done, children.QueryLocator = more.Done, more.QueryLocator
+			}
+			if err := c.FetchChildren(ctx, children.Records...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func withoutFetchChildren(o *callOptions) {
+	o.fetchChildren = false
+}
@@ -0,0 +1,71 @@
+package soapforce
+
+import (
+	"context"
+)
+
+// ResponseHeaders are the SOAP headers returned with a response.
+type ResponseHeaders struct {
+	// LimitInfo holds every limit reported in LimitInfoHeader, such as
+	// API_REQUESTS.
+	LimitInfo []*LimitInfo
+
+	// DebuggingInfo holds the debug log requested with SetDebuggingHeader.
+	DebuggingInfo *DebuggingInfo
+}
+
+// Limit returns the limit of the given type, e.g. "API_REQUESTS", or nil.
+func (h *ResponseHeaders) Limit(typ string) *LimitInfo {
+	if h == nil {
+		return nil
+	}
+	for _, l := range h.LimitInfo {
+		if l.Type_ == typ {
+			return l
+		}
+	}
+	return nil
+}
+
+// DebugLog returns the Apex debug log, if any.
+func (h *ResponseHeaders) DebugLog() string {
+	if h == nil || h.DebuggingInfo == nil {
+		return ""
+	}
+	return h.DebuggingInfo.DebugLog
+}
+
+func (h *ResponseHeaders) clone() *ResponseHeaders {
+	if h == nil {
+		return &ResponseHeaders{}
+	}
+	c := &ResponseHeaders{}
+	for _, l := range h.LimitInfo {
+		info := *l
+		c.LimitInfo = append(c.LimitInfo, &info)
+	}
+	if h.DebuggingInfo != nil {
+		info := *h.DebuggingInfo
+		c.DebuggingInfo = &info
+	}
+	return c
+}
+
+type responseHeadersKey struct{}
+
+// WithResponseHeaders returns a context that makes the calls using it store
+// the headers of their response in h. Use it to read the headers of one
+// particular call when the client is shared by several goroutines.
+//
+//	var h soapforce.ResponseHeaders
+//	res, err := client.QueryContext(soapforce.WithResponseHeaders(ctx, &h), q)
+//	fmt.Println(h.Limit("API_REQUESTS"))
+func WithResponseHeaders(ctx context.Context, h *ResponseHeaders) context.Context {
+	return context.WithValue(ctx, responseHeadersKey{}, h)
+}
+
+func captureResponseHeaders(ctx context.Context, h *ResponseHeaders) {
+	if dst, ok := ctx.Value(responseHeadersKey{}).(*ResponseHeaders); ok && dst != nil {
+		*dst = *h.clone()
+	}
+}
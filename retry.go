@@ -0,0 +1,311 @@
+package soapforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how SOAPClient retries transient failures.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// Jitter is the fraction of each backoff that is randomized, from 0 to 1.
+	Jitter float64
+
+	// RetryableCodes are the fault exception codes that trigger a retry.
+	RetryableCodes []ExceptionCode
+
+	// RetryableStatusCodes are the record level status codes, found in
+	// SaveResult, UpsertResult, DeleteResult and UndeleteResult errors,
+	// for which the failed records are sent again.
+	RetryableStatusCodes []StatusCode
+
+	// RetryNonIdempotent allows retrying calls other than queries,
+	// retrieves and describes after a failure of the whole call, as they
+	// may have taken effect on the server even though they failed. Records
+	// failed with a RetryableStatusCodes code are sent again regardless.
+	RetryNonIdempotent bool
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		RetryableCodes: []ExceptionCode{
+			ExceptionCodeSERVER_UNAVAILABLE,
+			ExceptionCodeREQUEST_LIMIT_EXCEEDED,
+			ExceptionCodeUNABLE_TO_LOCK_ROW,
+		},
+		RetryableStatusCodes: []StatusCode{
+			StatusCodeUNABLE_TO_LOCK_ROW,
+		},
+	}
+}
+
+// HTTPError is returned when the server answers with an error status and a
+// body that is not a SOAP fault.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status: %s", e.Status)
+}
+
+// backoff returns how long to wait after the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	jitter := math.Max(0, math.Min(1, p.Jitter))
+	d = d*(1-jitter) + d*jitter*rand.Float64()
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *RetryPolicy) retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	for _, code := range p.RetryableCodes {
+		if errors.Is(err, code) {
+			return true
+		}
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// also matches a *url.Error, which reports the state of the error it wraps
+	var netErr net.Error
+	return errors.As(err, &netErr) && (netErr.Timeout() || netErr.Temporary())
+}
+
+func (p *RetryPolicy) retryableStatus(errs []*Error) bool {
+	if len(errs) == 0 {
+		return false
+	}
+	for _, e := range errs {
+		if e.StatusCode == nil {
+			return false
+		}
+		found := false
+		for _, code := range p.RetryableStatusCodes {
+			if *e.StatusCode == code {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// rolledBack reports whether a record only failed because another record
+// in the same all-or-none call failed.
+func rolledBack(errs []*Error) bool {
+	for _, e := range errs {
+		if e.StatusCode == nil || *e.StatusCode != StatusCodeALL_OR_NONE_OPERATION_ROLLED_BACK {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
+
+// retryIndexes returns the positions of the records that should be sent
+// again. Records rolled back by an all-or-none call are retried along with
+// the ones that failed with a retryable status code.
+func (p *RetryPolicy) retryIndexes(errs [][]*Error) []int {
+	var retry, rolled []int
+	for i, e := range errs {
+		if p.retryableStatus(e) {
+			retry = append(retry, i)
+		} else if rolledBack(e) {
+			rolled = append(rolled, i)
+		}
+	}
+	if len(retry) == 0 {
+		return nil
+	}
+	indexes := append(retry, rolled...)
+	sort.Ints(indexes)
+	return indexes
+}
+
+// retryableRecords builds a request containing only the records of a DML
+// call whose results failed with a retryable status code. merge copies the
+// results of that request back into response.
+func (p *RetryPolicy) retryableRecords(request, response interface{}) (retry, retryResponse interface{}, merge func()) {
+	switch req := request.(type) {
+	case *Create:
+		res := response.(*CreateResponse)
+		indexes := p.retryIndexes(saveResultErrors(res.Result))
+		if len(indexes) == 0 || len(res.Result) != len(req.SObjects) {
+			return nil, nil, nil
+		}
+		sub := &Create{SObjects: pickSObjects(req.SObjects, indexes)}
+		subRes := new(CreateResponse)
+		return sub, subRes, func() {
+			for i, idx := range indexes {
+				if i < len(subRes.Result) {
+					res.Result[idx] = subRes.Result[i]
+				}
+			}
+		}
+	case *Update:
+		res := response.(*UpdateResponse)
+		indexes := p.retryIndexes(saveResultErrors(res.Result))
+		if len(indexes) == 0 || len(res.Result) != len(req.SObjects) {
+			return nil, nil, nil
+		}
+		sub := &Update{SObjects: pickSObjects(req.SObjects, indexes)}
+		subRes := new(UpdateResponse)
+		return sub, subRes, func() {
+			for i, idx := range indexes {
+				if i < len(subRes.Result) {
+					res.Result[idx] = subRes.Result[i]
+				}
+			}
+		}
+	case *Upsert:
+		res := response.(*UpsertResponse)
+		errs := make([][]*Error, len(res.Result))
+		for i, r := range res.Result {
+			errs[i] = r.Errors
+		}
+		indexes := p.retryIndexes(errs)
+		if len(indexes) == 0 || len(res.Result) != len(req.SObjects) {
+			return nil, nil, nil
+		}
+		sub := &Upsert{ExternalIDFieldName: req.ExternalIDFieldName, SObjects: pickSObjects(req.SObjects, indexes)}
+		subRes := new(UpsertResponse)
+		return sub, subRes, func() {
+			for i, idx := range indexes {
+				if i < len(subRes.Result) {
+					res.Result[idx] = subRes.Result[i]
+				}
+			}
+		}
+	case *Delete:
+		res := response.(*DeleteResponse)
+		errs := make([][]*Error, len(res.Result))
+		for i, r := range res.Result {
+			errs[i] = r.Errors
+		}
+		indexes := p.retryIndexes(errs)
+		if len(indexes) == 0 || len(res.Result) != len(req.Ids) {
+			return nil, nil, nil
+		}
+		sub := &Delete{Ids: pickIds(req.Ids, indexes)}
+		subRes := new(DeleteResponse)
+		return sub, subRes, func() {
+			for i, idx := range indexes {
+				if i < len(subRes.Result) {
+					res.Result[idx] = subRes.Result[i]
+				}
+			}
+		}
+	case *Undelete:
+		res := response.(*UndeleteResponse)
+		errs := make([][]*Error, len(res.Result))
+		for i, r := range res.Result {
+			errs[i] = r.Errors
+		}
+		indexes := p.retryIndexes(errs)
+		if len(indexes) == 0 || len(res.Result) != len(req.Ids) {
+			return nil, nil, nil
+		}
+		sub := &Undelete{Ids: pickIds(req.Ids, indexes)}
+		subRes := new(UndeleteResponse)
+		return sub, subRes, func() {
+			for i, idx := range indexes {
+				if i < len(subRes.Result) {
+					res.Result[idx] = subRes.Result[i]
+				}
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+func saveResultErrors(results []*SaveResult) [][]*Error {
+	errs := make([][]*Error, len(results))
+	for i, r := range results {
+		errs[i] = r.Errors
+	}
+	return errs
+}
+
+func pickSObjects(s []*SObject, indexes []int) []*SObject {
+	picked := make([]*SObject, len(indexes))
+	for i, idx := range indexes {
+		picked[i] = s[idx]
+	}
+	return picked
+}
+
+func pickIds(ids []string, indexes []int) []string {
+	picked := make([]string, len(indexes))
+	for i, idx := range indexes {
+		picked[i] = ids[idx]
+	}
+	return picked
+}
+
+// isIdempotent reports whether sending request twice has the same effect
+// as sending it once. Only calls that read data are.
+func isIdempotent(request interface{}) bool {
+	switch request.(type) {
+	case *Login, *GetUserInfo, *GetServerTimestamp,
+		*Query, *QueryAll, *QueryMore, *Retrieve, *Search, *GetDeleted, *GetUpdated,
+		*ExecuteListView, *FindDuplicates, *RenderEmailTemplate, *RetrieveQuickActionTemplates,
+		*DescribeSObject, *DescribeSObjects, *DescribeGlobal, *DescribeDataCategoryGroups,
+		*DescribeDataCategoryGroupStructures, *DescribeKnowledgeSettings, *DescribeFlexiPages,
+		*DescribeAppMenu, *DescribeGlobalTheme, *DescribeTheme, *DescribeLayout,
+		*DescribeSoftphoneLayout, *DescribeSearchLayouts, *DescribeSearchableEntities,
+		*DescribeSearchScopeOrder, *DescribeCompactLayouts, *DescribePathAssistants,
+		*DescribeApprovalLayout, *DescribeSoqlListViews, *DescribeSObjectListViews,
+		*DescribeTabs, *DescribeAllTabs, *DescribePrimaryCompactLayouts,
+		*DescribeQuickActions, *DescribeAvailableQuickActions, *DescribeVisualForce,
+		*DescribeNouns:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package soapforce
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const lockedRowResultXML = `<result><errors><message>unable to obtain exclusive access to this record</message>` +
+	`<statusCode>UNABLE_TO_LOCK_ROW</statusCode></errors><success>false</success></result>`
+
+const invalidFieldFaultXML = `<soapenv:Fault><faultcode>sf:INVALID_FIELD</faultcode>` +
+	`<faultstring>INVALID_FIELD: No such column 'Foo__c' on entity 'Account'</faultstring></soapenv:Fault>`
+
+func testRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = time.Millisecond
+	return p
+}
+
+// TestRecordRetryKeepsPartialResults checks that when the call retrying the
+// failed records fails, the results of the first call are still returned.
+func TestRecordRetryKeepsPartialResults(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, err := requestBody(r)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			writeEnvelope(w, "<createResponse>"+saveResultXML+lockedRowResultXML+"</createResponse>")
+		case 2:
+			if n := strings.Count(request, "<sObjects"); n != 1 || !strings.Contains(request, "Locked") {
+				t.Errorf("retry sent %d records: %s", n, request)
+			}
+			writeEnvelope(w, invalidFieldFaultXML)
+		default:
+			t.Errorf("unexpected request: %s", request)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(WithRetryPolicy(testRetryPolicy()))
+	c.SetServerUrl(server.URL)
+	c.SetLogger(ioutil.Discard)
+	c.SetAccessToken("sid")
+	results, err := c.Create([]*SObject{
+		{Type: "Account", Fields: map[string]interface{}{"Name": "Saved"}},
+		{Type: "Account", Fields: map[string]interface{}{"Name": "Locked"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if !results[0].Success || results[0].Id != "001000000000001AAA" {
+		t.Errorf("first result = %+v, want the saved record", results[0])
+	}
+	if results[1].Success || len(results[1].Errors) != 1 || *results[1].Errors[0].StatusCode != StatusCodeUNABLE_TO_LOCK_ROW {
+		t.Errorf("second result = %+v, want its UNABLE_TO_LOCK_ROW error", results[1])
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+// TestRecordRetryMergesResults checks that records saved by a retry replace
+// their failed results.
+func TestRecordRetryMergesResults(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			writeEnvelope(w, "<createResponse>"+lockedRowResultXML+saveResultXML+"</createResponse>")
+			return
+		}
+		writeEnvelope(w, "<createResponse>"+saveResultXML+"</createResponse>")
+	}))
+	defer server.Close()
+
+	c := NewClient(WithRetryPolicy(testRetryPolicy()))
+	c.SetServerUrl(server.URL)
+	c.SetLogger(ioutil.Discard)
+	c.SetAccessToken("sid")
+	results, err := c.Create([]*SObject{
+		{Type: "Account", Fields: map[string]interface{}{"Name": "Locked"}},
+		{Type: "Account", Fields: map[string]interface{}{"Name": "Saved"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for i, r := range results {
+		if !r.Success {
+			t.Errorf("result %d = %+v, want success", i, r)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
@@ -3,14 +3,18 @@ package soapforce
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/xml"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"reflect"
+	"sync"
 	"time"
 )
 
@@ -26,35 +30,26 @@ type SObject struct {
 	Id string `xml:"Id,omitempty"`
 
 	Fields map[string]interface{}
+
+	// types holds the xsi:type of the decoded fields until ConvertFieldTypes
+	// converts them.
+	types map[string]fieldType
+
+	// related is set on the parent records decoded from a query, e.g. the
+	// Account of a Contact, which are not sent back by Update.
+	related bool
 }
 
 func (s *SObject) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	start.Name.Local = "sObjects"
 	start.Name.Space = "urn:sobject.partner.soap.sforce.com"
-	e.EncodeToken(start)
-	e.EncodeElement(s.Type, xml.StartElement{Name: xml.Name{Local: "type"}})
-	if s.Id != "" {
-		e.EncodeElement(s.Id, xml.StartElement{Name: xml.Name{Local: "Id"}})
-	}
-	if s.FieldsToNull != nil {
-		for _, v := range s.FieldsToNull {
-			e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "fieldsToNull"}})
-		}
+	if err := e.EncodeToken(start); err != nil {
+		return err
 	}
-	for k, v := range s.Fields {
-		if _, ok := v.(string); ok {
-			e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: k}})
-		} else if obj, ok := v.(map[string]string); ok {
-			ref := xml.StartElement{Name: xml.Name{Local: k}}
-			e.EncodeToken(ref)
-			for innerKey, innerValue := range obj {
-				e.EncodeElement(innerValue, xml.StartElement{Name: xml.Name{Local: innerKey}})
-			}
-			e.EncodeToken(ref.End())
-		}
+	if err := encodeSObjectFields(e, s); err != nil {
+		return err
 	}
-	e.EncodeToken(start.End())
-	return nil
+	return e.EncodeToken(start.End())
 }
 
 func (s *SObject) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
@@ -87,35 +82,45 @@ func decodeSObject(d *xml.Decoder, s *SObject, objectName string) error {
 				} else if t.Name.Local == "type" {
 					s.Type = v
 				}
-			} else {
-				if len(t.Attr) > 0 {
-					a := t.Attr[0]
-					if a.Name.Local == "type" && a.Value == "sf:sObject" {
-						v := &SObject{}
-						err := decodeSObject(d, v, t.Name.Local)
-						if err != nil {
-							return err
-						}
-						s.Fields[t.Name.Local] = v
-					} else if a.Name.Local == "type" && a.Value == "QueryResult" {
-						v := &QueryResult{}
-						if err := d.DecodeElement(v, &t); err != nil {
-							return err
-						}
-						s.Fields[t.Name.Local] = v
-					} else {
-						var v string
-						if err := d.DecodeElement(&v, &t); err != nil {
-							return err
-						}
-						s.Fields[t.Name.Local] = v
-					}
-				} else {
-					var v string
-					if err := d.DecodeElement(&v, &t); err != nil {
-						return err
-					}
-					s.Fields[t.Name.Local] = v
+				continue
+			}
+			xsiType, null := "", false
+			for _, a := range t.Attr {
+				if a.Name.Local == "type" {
+					xsiType = a.Value
+				} else if a.Name.Local == "nil" && a.Value == "true" {
+					null = true
+				}
+			}
+			switch {
+			case xsiType == "sf:sObject":
+				v := &SObject{related: true}
+				err := decodeSObject(d, v, t.Name.Local)
+				if err != nil {
+					return err
+				}
+				s.Fields[t.Name.Local] = v
+			case xsiType == "QueryResult":
+				v := &QueryResult{}
+				if err := d.DecodeElement(v, &t); err != nil {
+					return err
+				}
+				s.Fields[t.Name.Local] = v
+			case isCompoundType(xsiType):
+				v, err := decodeCompound(d, &t, xsiType)
+				if err != nil {
+					return err
+				}
+				s.Fields[t.Name.Local] = ""
+				s.setFieldType(t.Name.Local, fieldType{xsiType: xsiType, compound: v})
+			default:
+				var v string
+				if err := d.DecodeElement(&v, &t); err != nil {
+					return err
+				}
+				s.Fields[t.Name.Local] = v
+				if xsiType != "" || null {
+					s.setFieldType(t.Name.Local, fieldType{xsiType: xsiType, null: null})
 				}
 			}
 		}
@@ -1286,11 +1291,11 @@ type DescribeSObjectResponse struct {
 type DescribeSObjects struct {
 	XMLName xml.Name `xml:"urn:partner.soap.sforce.com describeSObjects"`
 
-	SObjectType string `xml:"sObjectType,omitempty"`
+	SObjectType []string `xml:"sObjectType,omitempty"`
 }
 
 type DescribeSObjectsResponse struct {
-	Result *DescribeSObjectResult `xml:"result,omitempty"`
+	Result []*DescribeSObjectResult `xml:"result,omitempty"`
 }
 
 type DescribeGlobal struct {
@@ -1998,7 +2003,7 @@ type DuplicateRuleHeader struct {
 type LimitInfoHeader struct {
 	XMLName xml.Name `xml:"urn:partner.soap.sforce.com LimitInfoHeader"`
 
-	LimitInfo *LimitInfo `xml:"limitInfo,omitempty"`
+	LimitInfo []*LimitInfo `xml:"limitInfo,omitempty"`
 }
 
 type MruHeader struct {
@@ -4768,7 +4773,7 @@ type MalformedSearchFault struct {
 }
 
 type Soap struct {
-	client *SOAPClient
+	client         *SOAPClient
 	responseHeader *ResponseSOAPHeader
 }
 
@@ -4779,10 +4784,8 @@ func NewSoap(url string, tls bool, auth *BasicAuth) *Soap {
 	client := NewSOAPClient(url, tls, auth)
 
 	return &Soap{
-		client: client,
-		responseHeader: &ResponseSOAPHeader {
-			info: &LimitInfoHeader{},
-		},
+		client:         client,
+		responseHeader: &ResponseSOAPHeader{},
 	}
 }
 
@@ -4793,7 +4796,8 @@ func NewSoapWithTLSConfig(url string, tlsCfg *tls.Config, auth *BasicAuth) *Soap
 	client := NewSOAPClientWithTLSConfig(url, tlsCfg, auth)
 
 	return &Soap{
-		client: client,
+		client:         client,
+		responseHeader: &ResponseSOAPHeader{},
 	}
 }
 
@@ -4802,10 +4806,7 @@ func (service *Soap) AddHeader(header interface{}) {
 }
 
 func (service *Soap) SetHeader(headers []interface{}) {
-	service.ClearHeader()
-	for _, header := range headers {
-		service.AddHeader(header)
-	}
+	service.client.SetHeader(headers)
 }
 
 func (service *Soap) ClearHeader() {
@@ -4824,8 +4825,48 @@ func (service *Soap) SetGzip(gz bool) {
 	service.client.SetGzip(gz)
 }
 
+func (service *Soap) SetHTTPClient(c *http.Client) {
+	service.client.SetHTTPClient(c)
+}
+
+func (service *Soap) SetTransport(rt http.RoundTripper) {
+	service.client.SetTransport(rt)
+}
+
+func (service *Soap) SetConnectTimeout(d time.Duration) {
+	service.client.SetConnectTimeout(d)
+}
+
+func (service *Soap) SetReadTimeout(d time.Duration) {
+	service.client.SetReadTimeout(d)
+}
+
+func (service *Soap) SetTimeout(d time.Duration) {
+	service.client.SetTimeout(d)
+}
+
+func (service *Soap) SetSessionRenewer(fn func(ctx context.Context, headers []interface{}) error) {
+	service.client.SetSessionRenewer(fn)
+}
+
+func (service *Soap) AddInterceptor(interceptors ...Interceptor) {
+	service.client.AddInterceptor(interceptors...)
+}
+
+func (service *Soap) SetRetryPolicy(p *RetryPolicy) {
+	service.client.SetRetryPolicy(p)
+}
+
+func (service *Soap) SetTypedFields(typed bool) {
+	service.client.SetTypedFields(typed)
+}
+
 func (service *Soap) GetInfo() *LimitInfoHeader {
-	return service.responseHeader.info
+	return &LimitInfoHeader{LimitInfo: service.LastResponseHeaders().LimitInfo}
+}
+
+func (service *Soap) LastResponseHeaders() *ResponseHeaders {
+	return service.responseHeader.Last()
 }
 
 // Error can be either of the following types:
@@ -4835,8 +4876,12 @@ func (service *Soap) GetInfo() *LimitInfoHeader {
 //   - InvalidIdFault
 /* Login to the Salesforce.com SOAP Api */
 func (service *Soap) Login(request *Login) (*LoginResponse, error) {
+	return service.LoginContext(context.Background(), request)
+}
+
+func (service *Soap) LoginContext(ctx context.Context, request *Login) (*LoginResponse, error) {
 	response := new(LoginResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4850,8 +4895,12 @@ func (service *Soap) Login(request *Login) (*LoginResponse, error) {
 //   - UnexpectedErrorFault
 /* Describe an sObject */
 func (service *Soap) DescribeSObject(request *DescribeSObject) (*DescribeSObjectResponse, error) {
+	return service.DescribeSObjectContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSObjectContext(ctx context.Context, request *DescribeSObject) (*DescribeSObjectResponse, error) {
 	response := new(DescribeSObjectResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4865,8 +4914,12 @@ func (service *Soap) DescribeSObject(request *DescribeSObject) (*DescribeSObject
 //   - UnexpectedErrorFault
 /* Describe multiple sObjects (upto 100) */
 func (service *Soap) DescribeSObjects(request *DescribeSObjects) (*DescribeSObjectsResponse, error) {
+	return service.DescribeSObjectsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSObjectsContext(ctx context.Context, request *DescribeSObjects) (*DescribeSObjectsResponse, error) {
 	response := new(DescribeSObjectsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4879,8 +4932,12 @@ func (service *Soap) DescribeSObjects(request *DescribeSObjects) (*DescribeSObje
 //   - UnexpectedErrorFault
 /* Describe the Global state */
 func (service *Soap) DescribeGlobal(request *DescribeGlobal) (*DescribeGlobalResponse, error) {
+	return service.DescribeGlobalContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeGlobalContext(ctx context.Context, request *DescribeGlobal) (*DescribeGlobalResponse, error) {
 	response := new(DescribeGlobalResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4894,8 +4951,12 @@ func (service *Soap) DescribeGlobal(request *DescribeGlobal) (*DescribeGlobalRes
 //   - UnexpectedErrorFault
 /* Describe all the data category groups available for a given set of types */
 func (service *Soap) DescribeDataCategoryGroups(request *DescribeDataCategoryGroups) (*DescribeDataCategoryGroupsResponse, error) {
+	return service.DescribeDataCategoryGroupsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeDataCategoryGroupsContext(ctx context.Context, request *DescribeDataCategoryGroups) (*DescribeDataCategoryGroupsResponse, error) {
 	response := new(DescribeDataCategoryGroupsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4909,8 +4970,12 @@ func (service *Soap) DescribeDataCategoryGroups(request *DescribeDataCategoryGro
 //   - UnexpectedErrorFault
 /* Describe the data category group structures for a given set of pair of types and data category group name */
 func (service *Soap) DescribeDataCategoryGroupStructures(request *DescribeDataCategoryGroupStructures) (*DescribeDataCategoryGroupStructuresResponse, error) {
+	return service.DescribeDataCategoryGroupStructuresContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeDataCategoryGroupStructuresContext(ctx context.Context, request *DescribeDataCategoryGroupStructures) (*DescribeDataCategoryGroupStructuresResponse, error) {
 	response := new(DescribeDataCategoryGroupStructuresResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4923,8 +4988,12 @@ func (service *Soap) DescribeDataCategoryGroupStructures(request *DescribeDataCa
 //   - UnexpectedErrorFault
 /* Describes your Knowledge settings, such as if knowledgeEnabled is on or off, its default language and supported languages */
 func (service *Soap) DescribeKnowledgeSettings(request *DescribeKnowledgeSettings) (*DescribeKnowledgeSettingsResponse, error) {
+	return service.DescribeKnowledgeSettingsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeKnowledgeSettingsContext(ctx context.Context, request *DescribeKnowledgeSettings) (*DescribeKnowledgeSettingsResponse, error) {
 	response := new(DescribeKnowledgeSettingsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4938,8 +5007,12 @@ func (service *Soap) DescribeKnowledgeSettings(request *DescribeKnowledgeSetting
 //   - InvalidIdFault
 /* Describe a list of FlexiPage and their contents */
 func (service *Soap) DescribeFlexiPages(request *DescribeFlexiPages) (*DescribeFlexiPagesResponse, error) {
+	return service.DescribeFlexiPagesContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeFlexiPagesContext(ctx context.Context, request *DescribeFlexiPages) (*DescribeFlexiPagesResponse, error) {
 	response := new(DescribeFlexiPagesResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4952,8 +5025,12 @@ func (service *Soap) DescribeFlexiPages(request *DescribeFlexiPages) (*DescribeF
 //   - UnexpectedErrorFault
 /* Describe the items in an AppMenu */
 func (service *Soap) DescribeAppMenu(request *DescribeAppMenu) (*DescribeAppMenuResponse, error) {
+	return service.DescribeAppMenuContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeAppMenuContext(ctx context.Context, request *DescribeAppMenu) (*DescribeAppMenuResponse, error) {
 	response := new(DescribeAppMenuResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4966,8 +5043,12 @@ func (service *Soap) DescribeAppMenu(request *DescribeAppMenu) (*DescribeAppMenu
 //   - UnexpectedErrorFault
 /* Describe Gloal and Themes */
 func (service *Soap) DescribeGlobalTheme(request *DescribeGlobalTheme) (*DescribeGlobalThemeResponse, error) {
+	return service.DescribeGlobalThemeContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeGlobalThemeContext(ctx context.Context, request *DescribeGlobalTheme) (*DescribeGlobalThemeResponse, error) {
 	response := new(DescribeGlobalThemeResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4980,8 +5061,12 @@ func (service *Soap) DescribeGlobalTheme(request *DescribeGlobalTheme) (*Describ
 //   - UnexpectedErrorFault
 /* Describe Themes */
 func (service *Soap) DescribeTheme(request *DescribeTheme) (*DescribeThemeResponse, error) {
+	return service.DescribeThemeContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeThemeContext(ctx context.Context, request *DescribeTheme) (*DescribeThemeResponse, error) {
 	response := new(DescribeThemeResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -4996,8 +5081,12 @@ func (service *Soap) DescribeTheme(request *DescribeTheme) (*DescribeThemeRespon
 //   - InvalidIdFault
 /* Describe the layout of the given sObject or the given actionable global page. */
 func (service *Soap) DescribeLayout(request *DescribeLayout) (*DescribeLayoutResponse, error) {
+	return service.DescribeLayoutContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeLayoutContext(ctx context.Context, request *DescribeLayout) (*DescribeLayoutResponse, error) {
 	response := new(DescribeLayoutResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5010,8 +5099,12 @@ func (service *Soap) DescribeLayout(request *DescribeLayout) (*DescribeLayoutRes
 //   - UnexpectedErrorFault
 /* Describe the layout of the SoftPhone */
 func (service *Soap) DescribeSoftphoneLayout(request *DescribeSoftphoneLayout) (*DescribeSoftphoneLayoutResponse, error) {
+	return service.DescribeSoftphoneLayoutContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSoftphoneLayoutContext(ctx context.Context, request *DescribeSoftphoneLayout) (*DescribeSoftphoneLayoutResponse, error) {
 	response := new(DescribeSoftphoneLayoutResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5025,8 +5118,12 @@ func (service *Soap) DescribeSoftphoneLayout(request *DescribeSoftphoneLayout) (
 //   - UnexpectedErrorFault
 /* Describe the search view of an sObject */
 func (service *Soap) DescribeSearchLayouts(request *DescribeSearchLayouts) (*DescribeSearchLayoutsResponse, error) {
+	return service.DescribeSearchLayoutsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSearchLayoutsContext(ctx context.Context, request *DescribeSearchLayouts) (*DescribeSearchLayoutsResponse, error) {
 	response := new(DescribeSearchLayoutsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5036,8 +5133,12 @@ func (service *Soap) DescribeSearchLayouts(request *DescribeSearchLayouts) (*Des
 
 /* Describe a list of entity names that reflects the current user's searchable entities */
 func (service *Soap) DescribeSearchableEntities(request *DescribeSearchableEntities) (*DescribeSearchableEntitiesResponse, error) {
+	return service.DescribeSearchableEntitiesContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSearchableEntitiesContext(ctx context.Context, request *DescribeSearchableEntities) (*DescribeSearchableEntitiesResponse, error) {
 	response := new(DescribeSearchableEntitiesResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5047,8 +5148,12 @@ func (service *Soap) DescribeSearchableEntities(request *DescribeSearchableEntit
 
 /* Describe a list of objects representing the order and scope of objects on a users search result page */
 func (service *Soap) DescribeSearchScopeOrder(request *DescribeSearchScopeOrder) (*DescribeSearchScopeOrderResponse, error) {
+	return service.DescribeSearchScopeOrderContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSearchScopeOrderContext(ctx context.Context, request *DescribeSearchScopeOrder) (*DescribeSearchScopeOrderResponse, error) {
 	response := new(DescribeSearchScopeOrderResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5058,8 +5163,12 @@ func (service *Soap) DescribeSearchScopeOrder(request *DescribeSearchScopeOrder)
 
 /* Describe the compact layouts of the given sObject */
 func (service *Soap) DescribeCompactLayouts(request *DescribeCompactLayouts) (*DescribeCompactLayoutsResponse, error) {
+	return service.DescribeCompactLayoutsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeCompactLayoutsContext(ctx context.Context, request *DescribeCompactLayouts) (*DescribeCompactLayoutsResponse, error) {
 	response := new(DescribeCompactLayoutsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5069,8 +5178,12 @@ func (service *Soap) DescribeCompactLayouts(request *DescribeCompactLayouts) (*D
 
 /* Describe the Path Assistants for the given sObject and optionally RecordTypes */
 func (service *Soap) DescribePathAssistants(request *DescribePathAssistants) (*DescribePathAssistantsResponse, error) {
+	return service.DescribePathAssistantsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribePathAssistantsContext(ctx context.Context, request *DescribePathAssistants) (*DescribePathAssistantsResponse, error) {
 	response := new(DescribePathAssistantsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5080,8 +5193,12 @@ func (service *Soap) DescribePathAssistants(request *DescribePathAssistants) (*D
 
 /* Describe the approval layouts of the given sObject */
 func (service *Soap) DescribeApprovalLayout(request *DescribeApprovalLayout) (*DescribeApprovalLayoutResponse, error) {
+	return service.DescribeApprovalLayoutContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeApprovalLayoutContext(ctx context.Context, request *DescribeApprovalLayout) (*DescribeApprovalLayoutResponse, error) {
 	response := new(DescribeApprovalLayoutResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5095,8 +5212,12 @@ func (service *Soap) DescribeApprovalLayout(request *DescribeApprovalLayout) (*D
 //   - UnexpectedErrorFault
 /* Describe the ListViews as SOQL metadata for the generation of SOQL. */
 func (service *Soap) DescribeSoqlListViews(request *DescribeSoqlListViews) (*DescribeSoqlListViewsResponse, error) {
+	return service.DescribeSoqlListViewsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSoqlListViewsContext(ctx context.Context, request *DescribeSoqlListViews) (*DescribeSoqlListViewsResponse, error) {
 	response := new(DescribeSoqlListViewsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5106,8 +5227,12 @@ func (service *Soap) DescribeSoqlListViews(request *DescribeSoqlListViews) (*Des
 
 /* Execute the specified list view and return the presentation-ready results. */
 func (service *Soap) ExecuteListView(request *ExecuteListView) (*ExecuteListViewResponse, error) {
+	return service.ExecuteListViewContext(context.Background(), request)
+}
+
+func (service *Soap) ExecuteListViewContext(ctx context.Context, request *ExecuteListView) (*ExecuteListViewResponse, error) {
 	response := new(ExecuteListViewResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5121,8 +5246,12 @@ func (service *Soap) ExecuteListView(request *ExecuteListView) (*ExecuteListView
 //   - UnexpectedErrorFault
 /* Describe the ListViews of a SObject as SOQL metadata for the generation of SOQL. */
 func (service *Soap) DescribeSObjectListViews(request *DescribeSObjectListViews) (*DescribeSObjectListViewsResponse, error) {
+	return service.DescribeSObjectListViewsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeSObjectListViewsContext(ctx context.Context, request *DescribeSObjectListViews) (*DescribeSObjectListViewsResponse, error) {
 	response := new(DescribeSObjectListViewsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5135,8 +5264,12 @@ func (service *Soap) DescribeSObjectListViews(request *DescribeSObjectListViews)
 //   - UnexpectedErrorFault
 /* Describe the tabs that appear on a users page */
 func (service *Soap) DescribeTabs(request *DescribeTabs) (*DescribeTabsResponse, error) {
+	return service.DescribeTabsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeTabsContext(ctx context.Context, request *DescribeTabs) (*DescribeTabsResponse, error) {
 	response := new(DescribeTabsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5149,8 +5282,12 @@ func (service *Soap) DescribeTabs(request *DescribeTabs) (*DescribeTabsResponse,
 //   - UnexpectedErrorFault
 /* Describe all tabs available to a user */
 func (service *Soap) DescribeAllTabs(request *DescribeAllTabs) (*DescribeAllTabsResponse, error) {
+	return service.DescribeAllTabsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeAllTabsContext(ctx context.Context, request *DescribeAllTabs) (*DescribeAllTabsResponse, error) {
 	response := new(DescribeAllTabsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5160,8 +5297,12 @@ func (service *Soap) DescribeAllTabs(request *DescribeAllTabs) (*DescribeAllTabs
 
 /* Describe the primary compact layouts for the sObjects requested */
 func (service *Soap) DescribePrimaryCompactLayouts(request *DescribePrimaryCompactLayouts) (*DescribePrimaryCompactLayoutsResponse, error) {
+	return service.DescribePrimaryCompactLayoutsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribePrimaryCompactLayoutsContext(ctx context.Context, request *DescribePrimaryCompactLayouts) (*DescribePrimaryCompactLayoutsResponse, error) {
 	response := new(DescribePrimaryCompactLayoutsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5177,8 +5318,12 @@ func (service *Soap) DescribePrimaryCompactLayouts(request *DescribePrimaryCompa
 //   - InvalidFieldFault
 /* Create a set of new sObjects */
 func (service *Soap) Create(request *Create) (*CreateResponse, error) {
+	return service.CreateContext(context.Background(), request)
+}
+
+func (service *Soap) CreateContext(ctx context.Context, request *Create) (*CreateResponse, error) {
 	response := new(CreateResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5194,8 +5339,12 @@ func (service *Soap) Create(request *Create) (*CreateResponse, error) {
 //   - InvalidFieldFault
 /* Update a set of sObjects */
 func (service *Soap) Update(request *Update) (*UpdateResponse, error) {
+	return service.UpdateContext(context.Background(), request)
+}
+
+func (service *Soap) UpdateContext(ctx context.Context, request *Update) (*UpdateResponse, error) {
 	response := new(UpdateResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5211,8 +5360,12 @@ func (service *Soap) Update(request *Update) (*UpdateResponse, error) {
 //   - InvalidFieldFault
 /* Update or insert a set of sObjects based on object id */
 func (service *Soap) Upsert(request *Upsert) (*UpsertResponse, error) {
+	return service.UpsertContext(context.Background(), request)
+}
+
+func (service *Soap) UpsertContext(ctx context.Context, request *Upsert) (*UpsertResponse, error) {
 	response := new(UpsertResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5228,8 +5381,12 @@ func (service *Soap) Upsert(request *Upsert) (*UpsertResponse, error) {
 //   - InvalidFieldFault
 /* Merge and update a set of sObjects based on object id */
 func (service *Soap) Merge(request *Merge) (*MergeResponse, error) {
+	return service.MergeContext(context.Background(), request)
+}
+
+func (service *Soap) MergeContext(ctx context.Context, request *Merge) (*MergeResponse, error) {
 	response := new(MergeResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5242,8 +5399,12 @@ func (service *Soap) Merge(request *Merge) (*MergeResponse, error) {
 //   - UnexpectedErrorFault
 /* Delete a set of sObjects */
 func (service *Soap) Delete(request *Delete) (*DeleteResponse, error) {
+	return service.DeleteContext(context.Background(), request)
+}
+
+func (service *Soap) DeleteContext(ctx context.Context, request *Delete) (*DeleteResponse, error) {
 	response := new(DeleteResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5256,8 +5417,12 @@ func (service *Soap) Delete(request *Delete) (*DeleteResponse, error) {
 //   - UnexpectedErrorFault
 /* Undelete a set of sObjects */
 func (service *Soap) Undelete(request *Undelete) (*UndeleteResponse, error) {
+	return service.UndeleteContext(context.Background(), request)
+}
+
+func (service *Soap) UndeleteContext(ctx context.Context, request *Undelete) (*UndeleteResponse, error) {
 	response := new(UndeleteResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5270,8 +5435,12 @@ func (service *Soap) Undelete(request *Undelete) (*UndeleteResponse, error) {
 //   - UnexpectedErrorFault
 /* Empty a set of sObjects from the recycle bin */
 func (service *Soap) EmptyRecycleBin(request *EmptyRecycleBin) (*EmptyRecycleBinResponse, error) {
+	return service.EmptyRecycleBinContext(context.Background(), request)
+}
+
+func (service *Soap) EmptyRecycleBinContext(ctx context.Context, request *EmptyRecycleBin) (*EmptyRecycleBinResponse, error) {
 	response := new(EmptyRecycleBinResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5288,8 +5457,12 @@ func (service *Soap) EmptyRecycleBin(request *EmptyRecycleBin) (*EmptyRecycleBin
 //   - InvalidIdFault
 /* Get a set of sObjects */
 func (service *Soap) Retrieve(request *Retrieve) (*RetrieveResponse, error) {
+	return service.RetrieveContext(context.Background(), request)
+}
+
+func (service *Soap) RetrieveContext(ctx context.Context, request *Retrieve) (*RetrieveResponse, error) {
 	response := new(RetrieveResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5303,8 +5476,12 @@ func (service *Soap) Retrieve(request *Retrieve) (*RetrieveResponse, error) {
 //   - InvalidIdFault
 /* Submit an entity to a workflow process or process a workitem */
 func (service *Soap) Process(request *Process) (*ProcessResponse, error) {
+	return service.ProcessContext(context.Background(), request)
+}
+
+func (service *Soap) ProcessContext(ctx context.Context, request *Process) (*ProcessResponse, error) {
 	response := new(ProcessResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5317,8 +5494,12 @@ func (service *Soap) Process(request *Process) (*ProcessResponse, error) {
 //   - UnexpectedErrorFault
 /* convert a set of leads */
 func (service *Soap) ConvertLead(request *ConvertLead) (*ConvertLeadResponse, error) {
+	return service.ConvertLeadContext(context.Background(), request)
+}
+
+func (service *Soap) ConvertLeadContext(ctx context.Context, request *ConvertLead) (*ConvertLeadResponse, error) {
 	response := new(ConvertLeadResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5331,8 +5512,12 @@ func (service *Soap) ConvertLead(request *ConvertLead) (*ConvertLeadResponse, er
 //   - UnexpectedErrorFault
 /* Logout the current user, invalidating the current session. */
 func (service *Soap) Logout(request *Logout) (*LogoutResponse, error) {
+	return service.LogoutContext(context.Background(), request)
+}
+
+func (service *Soap) LogoutContext(ctx context.Context, request *Logout) (*LogoutResponse, error) {
 	response := new(LogoutResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5345,8 +5530,12 @@ func (service *Soap) Logout(request *Logout) (*LogoutResponse, error) {
 //   - UnexpectedErrorFault
 /* Logs out and invalidates session ids */
 func (service *Soap) InvalidateSessions(request *InvalidateSessions) (*InvalidateSessionsResponse, error) {
+	return service.InvalidateSessionsContext(context.Background(), request)
+}
+
+func (service *Soap) InvalidateSessionsContext(ctx context.Context, request *InvalidateSessions) (*InvalidateSessionsResponse, error) {
 	response := new(InvalidateSessionsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5360,8 +5549,12 @@ func (service *Soap) InvalidateSessions(request *InvalidateSessions) (*Invalidat
 //   - UnexpectedErrorFault
 /* Get the IDs for deleted sObjects */
 func (service *Soap) GetDeleted(request *GetDeleted) (*GetDeletedResponse, error) {
+	return service.GetDeletedContext(context.Background(), request)
+}
+
+func (service *Soap) GetDeletedContext(ctx context.Context, request *GetDeleted) (*GetDeletedResponse, error) {
 	response := new(GetDeletedResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5375,8 +5568,12 @@ func (service *Soap) GetDeleted(request *GetDeleted) (*GetDeletedResponse, error
 //   - UnexpectedErrorFault
 /* Get the IDs for updated sObjects */
 func (service *Soap) GetUpdated(request *GetUpdated) (*GetUpdatedResponse, error) {
+	return service.GetUpdatedContext(context.Background(), request)
+}
+
+func (service *Soap) GetUpdatedContext(ctx context.Context, request *GetUpdated) (*GetUpdatedResponse, error) {
 	response := new(GetUpdatedResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5394,8 +5591,12 @@ func (service *Soap) GetUpdated(request *GetUpdated) (*GetUpdatedResponse, error
 //   - InvalidQueryLocatorFault
 /* Create a Query Cursor */
 func (service *Soap) Query(request *Query) (*QueryResponse, error) {
+	return service.QueryContext(context.Background(), request)
+}
+
+func (service *Soap) QueryContext(ctx context.Context, request *Query) (*QueryResponse, error) {
 	response := new(QueryResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5413,8 +5614,12 @@ func (service *Soap) Query(request *Query) (*QueryResponse, error) {
 //   - InvalidQueryLocatorFault
 /* Create a Query Cursor, including deleted sObjects */
 func (service *Soap) QueryAll(request *QueryAll) (*QueryAllResponse, error) {
+	return service.QueryAllContext(context.Background(), request)
+}
+
+func (service *Soap) QueryAllContext(ctx context.Context, request *QueryAll) (*QueryAllResponse, error) {
 	response := new(QueryAllResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5430,8 +5635,12 @@ func (service *Soap) QueryAll(request *QueryAll) (*QueryAllResponse, error) {
 //   - MalformedQueryFault
 /* Gets the next batch of sObjects from a query */
 func (service *Soap) QueryMore(request *QueryMore) (*QueryMoreResponse, error) {
+	return service.QueryMoreContext(context.Background(), request)
+}
+
+func (service *Soap) QueryMoreContext(ctx context.Context, request *QueryMore) (*QueryMoreResponse, error) {
 	response := new(QueryMoreResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5447,8 +5656,12 @@ func (service *Soap) QueryMore(request *QueryMore) (*QueryMoreResponse, error) {
 //   - UnexpectedErrorFault
 /* Search for sObjects */
 func (service *Soap) Search(request *Search) (*SearchResponse, error) {
+	return service.SearchContext(context.Background(), request)
+}
+
+func (service *Soap) SearchContext(ctx context.Context, request *Search) (*SearchResponse, error) {
 	response := new(SearchResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5461,8 +5674,12 @@ func (service *Soap) Search(request *Search) (*SearchResponse, error) {
 //   - UnexpectedErrorFault
 /* Gets server timestamp */
 func (service *Soap) GetServerTimestamp(request *GetServerTimestamp) (*GetServerTimestampResponse, error) {
+	return service.GetServerTimestampContext(context.Background(), request)
+}
+
+func (service *Soap) GetServerTimestampContext(ctx context.Context, request *GetServerTimestamp) (*GetServerTimestampResponse, error) {
 	response := new(GetServerTimestampResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5477,8 +5694,12 @@ func (service *Soap) GetServerTimestamp(request *GetServerTimestamp) (*GetServer
 //   - UnexpectedErrorFault
 /* Set a user's password */
 func (service *Soap) SetPassword(request *SetPassword) (*SetPasswordResponse, error) {
+	return service.SetPasswordContext(context.Background(), request)
+}
+
+func (service *Soap) SetPasswordContext(ctx context.Context, request *SetPassword) (*SetPasswordResponse, error) {
 	response := new(SetPasswordResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5492,8 +5713,12 @@ func (service *Soap) SetPassword(request *SetPassword) (*SetPasswordResponse, er
 //   - UnexpectedErrorFault
 /* Reset a user's password */
 func (service *Soap) ResetPassword(request *ResetPassword) (*ResetPasswordResponse, error) {
+	return service.ResetPasswordContext(context.Background(), request)
+}
+
+func (service *Soap) ResetPasswordContext(ctx context.Context, request *ResetPassword) (*ResetPasswordResponse, error) {
 	response := new(ResetPasswordResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5506,8 +5731,12 @@ func (service *Soap) ResetPassword(request *ResetPassword) (*ResetPasswordRespon
 //   - UnexpectedErrorFault
 /* Returns standard information relevant to the current user */
 func (service *Soap) GetUserInfo(request *GetUserInfo) (*GetUserInfoResponse, error) {
+	return service.GetUserInfoContext(context.Background(), request)
+}
+
+func (service *Soap) GetUserInfoContext(ctx context.Context, request *GetUserInfo) (*GetUserInfoResponse, error) {
 	response := new(GetUserInfoResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5520,8 +5749,12 @@ func (service *Soap) GetUserInfo(request *GetUserInfo) (*GetUserInfoResponse, er
 //   - UnexpectedErrorFault
 /* Send existing draft EmailMessage */
 func (service *Soap) SendEmailMessage(request *SendEmailMessage) (*SendEmailMessageResponse, error) {
+	return service.SendEmailMessageContext(context.Background(), request)
+}
+
+func (service *Soap) SendEmailMessageContext(ctx context.Context, request *SendEmailMessage) (*SendEmailMessageResponse, error) {
 	response := new(SendEmailMessageResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5534,8 +5767,12 @@ func (service *Soap) SendEmailMessage(request *SendEmailMessage) (*SendEmailMess
 //   - UnexpectedErrorFault
 /* Send outbound email */
 func (service *Soap) SendEmail(request *SendEmail) (*SendEmailResponse, error) {
+	return service.SendEmailContext(context.Background(), request)
+}
+
+func (service *Soap) SendEmailContext(ctx context.Context, request *SendEmail) (*SendEmailResponse, error) {
 	response := new(SendEmailResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5548,8 +5785,12 @@ func (service *Soap) SendEmail(request *SendEmail) (*SendEmailResponse, error) {
 //   - UnexpectedErrorFault
 /* Perform a template merge on one or more blocks of text. */
 func (service *Soap) RenderEmailTemplate(request *RenderEmailTemplate) (*RenderEmailTemplateResponse, error) {
+	return service.RenderEmailTemplateContext(context.Background(), request)
+}
+
+func (service *Soap) RenderEmailTemplateContext(ctx context.Context, request *RenderEmailTemplate) (*RenderEmailTemplateResponse, error) {
 	response := new(RenderEmailTemplateResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5559,8 +5800,12 @@ func (service *Soap) RenderEmailTemplate(request *RenderEmailTemplate) (*RenderE
 
 /* Perform a series of predefined actions such as quick create or log a task */
 func (service *Soap) PerformQuickActions(request *PerformQuickActions) (*PerformQuickActionsResponse, error) {
+	return service.PerformQuickActionsContext(context.Background(), request)
+}
+
+func (service *Soap) PerformQuickActionsContext(ctx context.Context, request *PerformQuickActions) (*PerformQuickActionsResponse, error) {
 	response := new(PerformQuickActionsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5570,8 +5815,12 @@ func (service *Soap) PerformQuickActions(request *PerformQuickActions) (*Perform
 
 /* Describe the details of a series of quick actions */
 func (service *Soap) DescribeQuickActions(request *DescribeQuickActions) (*DescribeQuickActionsResponse, error) {
+	return service.DescribeQuickActionsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeQuickActionsContext(ctx context.Context, request *DescribeQuickActions) (*DescribeQuickActionsResponse, error) {
 	response := new(DescribeQuickActionsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5581,8 +5830,12 @@ func (service *Soap) DescribeQuickActions(request *DescribeQuickActions) (*Descr
 
 /* Describe the details of a series of quick actions available for the given contextType */
 func (service *Soap) DescribeAvailableQuickActions(request *DescribeAvailableQuickActions) (*DescribeAvailableQuickActionsResponse, error) {
+	return service.DescribeAvailableQuickActionsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeAvailableQuickActionsContext(ctx context.Context, request *DescribeAvailableQuickActions) (*DescribeAvailableQuickActionsResponse, error) {
 	response := new(DescribeAvailableQuickActionsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5592,8 +5845,12 @@ func (service *Soap) DescribeAvailableQuickActions(request *DescribeAvailableQui
 
 /* Retreive the template sobjects, if appropriate, for the given quick action names in a given context */
 func (service *Soap) RetrieveQuickActionTemplates(request *RetrieveQuickActionTemplates) (*RetrieveQuickActionTemplatesResponse, error) {
+	return service.RetrieveQuickActionTemplatesContext(context.Background(), request)
+}
+
+func (service *Soap) RetrieveQuickActionTemplatesContext(ctx context.Context, request *RetrieveQuickActionTemplates) (*RetrieveQuickActionTemplatesResponse, error) {
 	response := new(RetrieveQuickActionTemplatesResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5603,8 +5860,12 @@ func (service *Soap) RetrieveQuickActionTemplates(request *RetrieveQuickActionTe
 
 /* Describe visualforce for an org */
 func (service *Soap) DescribeVisualForce(request *DescribeVisualForce) (*DescribeVisualForceResponse, error) {
+	return service.DescribeVisualForceContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeVisualForceContext(ctx context.Context, request *DescribeVisualForce) (*DescribeVisualForceResponse, error) {
 	response := new(DescribeVisualForceResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5619,8 +5880,12 @@ func (service *Soap) DescribeVisualForce(request *DescribeVisualForce) (*Describ
 //   - InvalidFieldFault
 /* Find duplicates for a set of sObjects */
 func (service *Soap) FindDuplicates(request *FindDuplicates) (*FindDuplicatesResponse, error) {
+	return service.FindDuplicatesContext(context.Background(), request)
+}
+
+func (service *Soap) FindDuplicatesContext(ctx context.Context, request *FindDuplicates) (*FindDuplicatesResponse, error) {
 	response := new(FindDuplicatesResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5630,8 +5895,12 @@ func (service *Soap) FindDuplicates(request *FindDuplicates) (*FindDuplicatesRes
 
 /* Return the renameable nouns from the server for use in presentation using the salesforce grammar engine */
 func (service *Soap) DescribeNouns(request *DescribeNouns) (*DescribeNounsResponse, error) {
+	return service.DescribeNounsContext(context.Background(), request)
+}
+
+func (service *Soap) DescribeNounsContext(ctx context.Context, request *DescribeNouns) (*DescribeNounsResponse, error) {
 	response := new(DescribeNounsResponse)
-	err := service.client.Call(request, response, service.responseHeader)
+	err := service.client.CallContext(ctx, request, response, service.responseHeader)
 	if err != nil {
 		return nil, err
 	}
@@ -5647,10 +5916,52 @@ func (service *Soap) GetServerUrl() string {
 	return service.client.GetServerUrl()
 }
 
-var timeout = time.Duration(30 * time.Second)
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultReadTimeout    = time.Duration(0)
+	DefaultTimeout        = time.Duration(0)
+)
+
+type transportKey struct {
+	insecureSkipVerify bool
+	connectTimeout     time.Duration
+	readTimeout        time.Duration
+}
+
+var (
+	sharedTransportsMu sync.Mutex
+	sharedTransports   = map[transportKey]*http.Transport{}
+)
+
+// sharedTransport returns a pooled transport shared by every SOAPClient
+// created with the same TLS verification mode and timeouts.
+func sharedTransport(key transportKey) *http.Transport {
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+	tr, ok := sharedTransports[key]
+	if !ok {
+		tr = newTransport(&tls.Config{InsecureSkipVerify: key.insecureSkipVerify}, key.connectTimeout, key.readTimeout)
+		sharedTransports[key] = tr
+	}
+	return tr
+}
 
-func dialTimeout(network, addr string) (net.Conn, error) {
-	return net.DialTimeout(network, addr, timeout)
+func newTransport(tlsCfg *tls.Config, connectTimeout, readTimeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
 }
 
 type SOAPEnvelope struct {
@@ -5662,12 +5973,27 @@ type SOAPEnvelope struct {
 type SOAPHeader struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
 
-	Items []interface{} `xml:",omitempty"`
-	response *ResponseSOAPHeader
+	Items    []interface{} `xml:",omitempty"`
+	response *ResponseHeaders
 }
 
+// ResponseSOAPHeader keeps the headers of the most recent response.
 type ResponseSOAPHeader struct {
-	info *LimitInfoHeader
+	mu   sync.RWMutex
+	last *ResponseHeaders
+}
+
+func (h *ResponseSOAPHeader) set(headers *ResponseHeaders) {
+	h.mu.Lock()
+	h.last = headers
+	h.mu.Unlock()
+}
+
+// Last returns a copy of the headers of the most recent response.
+func (h *ResponseSOAPHeader) Last() *ResponseHeaders {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.last.clone()
 }
 
 type SOAPBody struct {
@@ -5680,10 +6006,10 @@ type SOAPBody struct {
 type SOAPFault struct {
 	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
 
-	Code   string `xml:"faultcode,omitempty"`
-	String string `xml:"faultstring,omitempty"`
-	Actor  string `xml:"faultactor,omitempty"`
-	Detail string `xml:"detail,omitempty"`
+	Code   string           `xml:"faultcode,omitempty"`
+	String string           `xml:"faultstring,omitempty"`
+	Actor  string           `xml:"faultactor,omitempty"`
+	Detail *SOAPFaultDetail `xml:"detail,omitempty"`
 }
 
 const (
@@ -5733,7 +6059,11 @@ type BasicAuth struct {
 	Password string
 }
 
+// SOAPClient is safe for concurrent use. Its settings can be changed while
+// calls are in flight; each call uses the settings in place when it starts.
 type SOAPClient struct {
+	mu sync.RWMutex
+
 	url     string
 	tlsCfg  *tls.Config
 	auth    *BasicAuth
@@ -5741,6 +6071,33 @@ type SOAPClient struct {
 	logger  io.Writer
 	debug   bool
 	gzip    bool
+
+	httpClient     *http.Client
+	transport      http.RoundTripper
+	ownTransport   bool
+	sharedTLS      bool
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	timeout        time.Duration
+
+	sessionRenewer func(ctx context.Context, headers []interface{}) error
+	retryPolicy    *RetryPolicy
+	interceptors   []Interceptor
+	typedFields    bool
+}
+
+type renewingSessionKey struct{}
+
+// withRenewingSession marks ctx so that calls made while renewing a session
+// do not try to renew it again. The options of the failed call are dropped.
+func withRenewingSession(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, callOptionsKey{}, (*callOptions)(nil))
+	return context.WithValue(ctx, renewingSessionKey{}, true)
+}
+
+func isRenewingSession(ctx context.Context) bool {
+	renewing, _ := ctx.Value(renewingSessionKey{}).(bool)
+	return renewing
 }
 
 // **********
@@ -5784,9 +6141,8 @@ func NewWSSSecurityHeader(user, pass, mustUnderstand string) *WSSSecurityHeader
 
 func (b *SOAPHeader) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var (
-		token    xml.Token
-		err      error
-		consumed bool
+		token xml.Token
+		err   error
 	)
 
 Loop:
@@ -5801,16 +6157,23 @@ Loop:
 
 		switch se := token.(type) {
 		case xml.StartElement:
-			if consumed {
-				return xml.UnmarshalError("Found multiple elements inside SOAP body; not wrapped-document/literal WS-I compliant")
-			} else {
-				if se.Name.Local == "LimitInfoHeader" && b.response.info != nil {
-					if err = d.DecodeElement(b.response.info, &se); err != nil {
-						return err
-					}
+			switch {
+			case b.response != nil && se.Name.Local == "LimitInfoHeader":
+				info := &LimitInfoHeader{}
+				if err = d.DecodeElement(info, &se); err != nil {
+					return err
+				}
+				b.response.LimitInfo = append(b.response.LimitInfo, info.LimitInfo...)
+			case b.response != nil && se.Name.Local == "DebuggingInfo":
+				info := &DebuggingInfo{}
+				if err = d.DecodeElement(info, &se); err != nil {
+					return err
+				}
+				b.response.DebuggingInfo = info
+			default:
+				if err = d.Skip(); err != nil {
+					return err
 				}
-
-				consumed = true
 			}
 		case xml.EndElement:
 			break Loop
@@ -5877,50 +6240,343 @@ func NewSOAPClient(url string, insecureSkipVerify bool, auth *BasicAuth) *SOAPCl
 	tlsCfg := &tls.Config{
 		InsecureSkipVerify: insecureSkipVerify,
 	}
-	return NewSOAPClientWithTLSConfig(url, tlsCfg, auth)
+	client := NewSOAPClientWithTLSConfig(url, tlsCfg, auth)
+	client.sharedTLS = true
+	return client
 }
 
 func NewSOAPClientWithTLSConfig(url string, tlsCfg *tls.Config, auth *BasicAuth) *SOAPClient {
 	return &SOAPClient{
-		url:    url,
-		tlsCfg: tlsCfg,
-		auth:   auth,
-		logger: os.Stdout,
-		debug:  false,
-		gzip:   true,
+		url:            url,
+		tlsCfg:         tlsCfg,
+		auth:           auth,
+		logger:         &lockedWriter{w: os.Stdout},
+		debug:          false,
+		gzip:           true,
+		connectTimeout: DefaultConnectTimeout,
+		readTimeout:    DefaultReadTimeout,
+		timeout:        DefaultTimeout,
+	}
+}
+
+// SetHTTPClient makes the client send every request through c. The
+// timeouts configured on the SOAPClient are not applied to c.
+func (s *SOAPClient) SetHTTPClient(c *http.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpClient = c
+}
+
+// SetTransport replaces the pooled default transport with rt.
+func (s *SOAPClient) SetTransport(rt http.RoundTripper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transport = rt
+	s.ownTransport = false
+}
+
+func (s *SOAPClient) SetConnectTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connectTimeout = d
+	s.resetTransport()
+}
+
+func (s *SOAPClient) SetReadTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readTimeout = d
+	s.resetTransport()
+}
+
+func (s *SOAPClient) SetTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeout = d
+}
+
+// resetTransport drops a transport built by the SOAPClient itself so that it
+// is rebuilt with the current timeouts. Injected transports are kept. The
+// caller must hold s.mu.
+func (s *SOAPClient) resetTransport() {
+	if s.ownTransport {
+		s.transport = nil
+		s.ownTransport = false
+	}
+}
+
+func (s *SOAPClient) getHTTPClient() *http.Client {
+	s.mu.RLock()
+	if s.httpClient != nil || s.transport != nil {
+		defer s.mu.RUnlock()
+		if s.httpClient != nil {
+			return s.httpClient
+		}
+		return &http.Client{
+			Transport: s.transport,
+			Timeout:   s.timeout,
+		}
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.httpClient != nil {
+		return s.httpClient
+	}
+	if s.transport == nil {
+		if s.sharedTLS {
+			s.transport = sharedTransport(transportKey{
+				insecureSkipVerify: s.tlsCfg.InsecureSkipVerify,
+				connectTimeout:     s.connectTimeout,
+				readTimeout:        s.readTimeout,
+			})
+		} else {
+			s.transport = newTransport(s.tlsCfg, s.connectTimeout, s.readTimeout)
+		}
+		s.ownTransport = true
+	}
+	return &http.Client{
+		Transport: s.transport,
+		Timeout:   s.timeout,
+	}
+}
+
+// getVerifyingHTTPClient returns the client for requests to the OAuth
+// endpoints. A client or transport set by the caller is used as is, but the
+// default transport verifies certificates even when the SOAP calls skip
+// verification, so that credentials are never sent to an unverified server.
+func (s *SOAPClient) getVerifyingHTTPClient() *http.Client {
+	s.mu.RLock()
+	custom := s.httpClient != nil || s.transport != nil && !s.ownTransport
+	insecure := s.tlsCfg != nil && s.tlsCfg.InsecureSkipVerify
+	key := transportKey{
+		connectTimeout: s.connectTimeout,
+		readTimeout:    s.readTimeout,
+	}
+	timeout := s.timeout
+	s.mu.RUnlock()
+
+	if custom || !insecure {
+		return s.getHTTPClient()
+	}
+	return &http.Client{
+		Transport: sharedTransport(key),
+		Timeout:   timeout,
 	}
 }
 
 func (s *SOAPClient) SetDebug(debug bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.debug = debug
 }
 
 func (s *SOAPClient) SetLogger(logger io.Writer) {
-	s.logger = logger
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = &lockedWriter{w: logger}
 }
 
 func (s *SOAPClient) SetGzip(gz bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.gzip = gz
 }
 
+// AddHeader copies the headers on write, so calls in flight keep the
+// headers they started with.
 func (s *SOAPClient) AddHeader(header interface{}) {
-	s.headers = append(s.headers, header)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	headers := make([]interface{}, len(s.headers), len(s.headers)+1)
+	copy(headers, s.headers)
+	s.headers = append(headers, header)
+}
+
+func (s *SOAPClient) SetHeader(headers []interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headers = append([]interface{}(nil), headers...)
 }
 
 func (s *SOAPClient) ClearHeader() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.headers = nil
 }
 
+// AddInterceptor appends interceptors to the chain wrapped around every
+// round trip. The first interceptor added is the outermost one.
+func (s *SOAPClient) AddInterceptor(interceptors ...Interceptor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chain := make([]Interceptor, len(s.interceptors), len(s.interceptors)+len(interceptors))
+	copy(chain, s.interceptors)
+	s.interceptors = append(chain, interceptors...)
+}
+
+// SetRetryPolicy enables retries of transient failures. nil disables them.
+func (s *SOAPClient) SetRetryPolicy(p *RetryPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retryPolicy = p
+}
+
+// SetTypedFields makes every call decode SObject fields into Go values
+// matching their xsi:type, see SObject.ConvertFieldTypes.
+func (s *SOAPClient) SetTypedFields(typed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typedFields = typed
+}
+
+// SetSessionRenewer registers fn to be called once when a request fails with
+// INVALID_SESSION_ID. headers are the headers the failed request was sent
+// with. If fn succeeds the request is replayed with the current headers.
+func (s *SOAPClient) SetSessionRenewer(fn func(ctx context.Context, headers []interface{}) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionRenewer = fn
+}
+
+// soapClientConfig is a snapshot of the settings used by one call.
+type soapClientConfig struct {
+	url            string
+	auth           *BasicAuth
+	headers        []interface{}
+	logger         io.Writer
+	debug          bool
+	gzip           bool
+	sessionRenewer func(ctx context.Context, headers []interface{}) error
+	retryPolicy    *RetryPolicy
+	interceptors   []Interceptor
+	typedFields    bool
+}
+
+func (s *SOAPClient) config() soapClientConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return soapClientConfig{
+		url:            s.url,
+		auth:           s.auth,
+		headers:        s.headers,
+		logger:         s.logger,
+		debug:          s.debug,
+		gzip:           s.gzip,
+		sessionRenewer: s.sessionRenewer,
+		retryPolicy:    s.retryPolicy,
+		interceptors:   s.interceptors,
+		typedFields:    s.typedFields,
+	}
+}
+
+// lockedWriter serializes the debug output of concurrent calls.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
 func (s *SOAPClient) Call(request, response interface{}, responseHeader *ResponseSOAPHeader) error {
+	return s.CallContext(context.Background(), request, response, responseHeader)
+}
+
+func (s *SOAPClient) CallContext(ctx context.Context, request, response interface{}, responseHeader *ResponseSOAPHeader) error {
+	p := s.config().retryPolicy
+	if p == nil {
+		return s.callWithSession(ctx, request, response, responseHeader)
+	}
+	retryCall := p.RetryNonIdempotent || callOptionsFrom(ctx).retryNonIdempotent || isIdempotent(request)
+	retryable := func(attempt int, err error) bool {
+		return retryCall && attempt < p.MaxAttempts && p.retryableError(err)
+	}
+
+	attempt := 1
+	for {
+		err := s.callWithSession(ctx, request, response, responseHeader)
+		if err == nil {
+			break
+		}
+		if !retryable(attempt, err) {
+			return err
+		}
+		if werr := p.wait(ctx, attempt); werr != nil {
+			return werr
+		}
+		attempt++
+	}
+
+	// Records that failed on their own, e.g. with UNABLE_TO_LOCK_ROW, are
+	// sent again without the ones that already succeeded. The response
+	// already tells which records were saved, so when a retry can't be sent
+	// it is returned as is, the remaining records keeping their errors.
+	for attempt < p.MaxAttempts {
+		retry, retryResponse, merge := p.retryableRecords(request, response)
+		if retry == nil {
+			break
+		}
+		if p.wait(ctx, attempt) != nil {
+			break
+		}
+		attempt++
+		if err := s.callWithSession(ctx, retry, retryResponse, responseHeader); err != nil {
+			if !retryable(attempt, err) {
+				break
+			}
+			continue
+		}
+		merge()
+	}
+	return nil
+}
+
+func (s *SOAPClient) callWithSession(ctx context.Context, request, response interface{}, responseHeader *ResponseSOAPHeader) error {
+	overrides := callOptionsFrom(ctx).headers
+	cfg := s.config()
+	headers := mergeHeaders(cfg.headers, overrides)
+	err := s.call(ctx, cfg, headers, request, response, responseHeader)
+	if err == nil || cfg.sessionRenewer == nil || isRenewingSession(ctx) {
+		return err
+	}
+	if !errors.Is(err, ExceptionCodeINVALID_SESSION_ID) {
+		return err
+	}
+	if rerr := cfg.sessionRenewer(withRenewingSession(ctx), headers); rerr != nil {
+		return rerr
+	}
+	cfg = s.config()
+	return s.call(ctx, cfg, mergeHeaders(cfg.headers, overrides), request, response, responseHeader)
+}
+
+func (s *SOAPClient) call(ctx context.Context, cfg soapClientConfig, headers []interface{}, request, response interface{}, responseHeader *ResponseSOAPHeader) error {
+	info := &CallInfo{
+		Operation: operationName(request),
+		Request:   request,
+		Headers:   headers,
+		Response:  response,
+	}
+	invoker := func(ctx context.Context, info *CallInfo) error {
+		return s.roundTrip(ctx, cfg, info, responseHeader)
+	}
+	return chainInterceptors(cfg.interceptors, invoker)(ctx, info)
+}
+
+func (s *SOAPClient) roundTrip(ctx context.Context, cfg soapClientConfig, info *CallInfo, responseHeader *ResponseSOAPHeader) error {
 	envelope := SOAPEnvelope{}
 
-	if s.headers != nil && len(s.headers) > 0 {
-		soapHeader := &SOAPHeader{Items: make([]interface{}, len(s.headers))}
-		copy(soapHeader.Items, s.headers)
+	if len(info.Headers) > 0 {
+		soapHeader := &SOAPHeader{Items: make([]interface{}, len(info.Headers))}
+		copy(soapHeader.Items, info.Headers)
 		envelope.Header = soapHeader
 	}
 
-	envelope.Body.Content = request
+	envelope.Body.Content = info.Request
 	buffer := new(bytes.Buffer)
 
 	encoder := xml.NewEncoder(buffer)
@@ -5934,68 +6590,89 @@ func (s *SOAPClient) Call(request, response interface{}, responseHeader *Respons
 		return err
 	}
 
-	if s.debug {
-		s.logger.Write(buffer.Bytes())
-		s.logger.Write([]byte("\n"))
+	info.RequestEnvelope = buffer.Bytes()
+
+	if cfg.debug {
+		cfg.logger.Write(append(defaultRedactor.redact(buffer.Bytes()), '\n'))
 	}
 
-	req, err := s.createRequest(buffer)
+	req, err := createRequest(ctx, cfg, buffer)
 	if err != nil {
 		return err
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: s.tlsCfg,
-		Dial:            dialTimeout,
-	}
-
-	client := &http.Client{Transport: tr}
-	res, err := client.Do(req)
+	res, err := s.getHTTPClient().Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 	defer res.Body.Close()
+	info.StatusCode = res.StatusCode
 
 	rawbody, err := getRawBody(res)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	if s.debug {
-		s.logger.Write(rawbody)
-		s.logger.Write([]byte("\n"))
+	info.ResponseEnvelope = rawbody
+
+	if cfg.debug {
+		cfg.logger.Write(append(defaultRedactor.redact(rawbody), '\n'))
 	}
 
 	respEnvelope := new(SOAPEnvelope)
-	responseHeader.info = &LimitInfoHeader{}
-	header := SOAPHeader{response: responseHeader}
+	info.ResponseHeaders = &ResponseHeaders{}
+	header := SOAPHeader{response: info.ResponseHeaders}
 	respEnvelope.Header = &header
-	respEnvelope.Body = SOAPBody{Content: response}
+	respEnvelope.Body = SOAPBody{Content: info.Response}
 	err = xml.Unmarshal(rawbody, respEnvelope)
 	if err != nil {
+		if res.StatusCode >= 400 {
+			return &HTTPError{StatusCode: res.StatusCode, Status: res.Status, Body: rawbody}
+		}
 		return err
 	}
+	responseHeader.set(info.ResponseHeaders)
+	captureResponseHeaders(ctx, info.ResponseHeaders)
 
 	fault := respEnvelope.Body.Fault
 	if fault != nil {
 		return fault
 	}
+	if res.StatusCode >= 400 {
+		return &HTTPError{StatusCode: res.StatusCode, Status: res.Status, Body: rawbody}
+	}
 
+	if cfg.typedFields || callOptionsFrom(ctx).typedFields {
+		return convertFieldTypes(reflect.ValueOf(info.Response))
+	}
 	return nil
 }
 
 func (s *SOAPClient) SetServerUrl(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.url = url
 }
 
 func (s *SOAPClient) GetServerUrl() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.url
 }
 
-func (s *SOAPClient) createRequest(buffer *bytes.Buffer) (*http.Request, error) {
+func createRequest(ctx context.Context, cfg soapClientConfig, buffer *bytes.Buffer) (*http.Request, error) {
 	var req *http.Request
 	var err error
-	if s.gzip {
+	if cfg.gzip {
 		gzipBuffer := new(bytes.Buffer)
 		gw := gzip.NewWriter(gzipBuffer)
 		_, err = gw.Write(buffer.Bytes())
@@ -6006,28 +6683,27 @@ func (s *SOAPClient) createRequest(buffer *bytes.Buffer) (*http.Request, error)
 		if err != nil {
 			return nil, err
 		}
-		req, err = http.NewRequest("POST", s.url, gzipBuffer)
+		req, err = http.NewRequestWithContext(ctx, "POST", cfg.url, gzipBuffer)
 		if err != nil {
 			return nil, err
 		}
 		req.Header.Add("Content-Encoding", "gzip")
 		req.Header.Add("Accept-Encoding", "gzip")
 	} else {
-		req, err = http.NewRequest("POST", s.url, buffer)
+		req, err = http.NewRequestWithContext(ctx, "POST", cfg.url, buffer)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if s.auth != nil {
-		req.SetBasicAuth(s.auth.Login, s.auth.Password)
+	if cfg.auth != nil {
+		req.SetBasicAuth(cfg.auth.Login, cfg.auth.Password)
 	}
 
 	req.Header.Add("Content-Type", "text/xml; charset=\"utf-8\"")
 	req.Header.Add("SOAPAction", "''")
 
 	req.Header.Set("User-Agent", "gowsdl/0.1")
-	req.Close = true
 	return req, nil
 }
 
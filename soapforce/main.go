@@ -165,7 +165,7 @@ func login() {
 	client.SetClientId(os.Getenv("SALESFORCE_CLIENT_ID"))
 	client.SetClientSecret(os.Getenv("SALESFORCE_CLIENT_SECRET"))
 	client.SetDebug(true)
-	err := client.LoginWithOAuth(os.Getenv("SALESFORCE_USERNAME"), os.Getenv("SALESFORCE_PASSWORD"))
+	_, err := client.LoginWithOAuth(os.Getenv("SALESFORCE_USERNAME"), os.Getenv("SALESFORCE_PASSWORD"))
 	if err != nil {
 		panic(err)
 	}
@@ -175,7 +175,7 @@ func refresh() {
 	client := soapforce.NewClient()
 	client.SetClientId(os.Getenv("SALESFORCE_CLIENT_ID"))
 	client.SetClientSecret(os.Getenv("SALESFORCE_CLIENT_SECRET"))
-	err := client.Refresh(os.Getenv("REFRESH_TOKEN"))
+	_, err := client.Refresh(os.Getenv("REFRESH_TOKEN"))
 	if err != nil {
 		panic(err)
 	}
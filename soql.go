@@ -0,0 +1,484 @@
+package soapforce
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SOQL builds a SOQL query. Values are always written as escaped literals
+// and field and object names are checked, so user input can't change the
+// structure of the query.
+//
+//	q := soapforce.Select("Id", "Name", "Owner.Name").
+//		From("Account").
+//		Where(soapforce.And(
+//			soapforce.Eq("Name", name),
+//			soapforce.Ge("CreatedDate", soapforce.LastNDays(30)),
+//		)).
+//		OrderByDesc("CreatedDate").
+//		Limit(10)
+//	soql, err := q.Build()
+//	res, err := client.Query(soql)
+type SOQL struct {
+	fields    []string
+	from      string
+	where     []Condition
+	groupBy   []string
+	having    []Condition
+	orderBy   []string
+	limit     int
+	offset    int
+	forUpdate bool
+	err       error
+}
+
+// Select starts a query selecting fields. A field can be a relationship
+// path such as Account.Name, or a function such as COUNT(Id), with an
+// optional alias.
+func Select(fields ...string) *SOQL {
+	q := &SOQL{}
+	for _, f := range fields {
+		q.field(selectPattern, f)
+		q.fields = append(q.fields, f)
+	}
+	return q
+}
+
+// Subquery selects the child records returned by sub, whose From is a child
+// relationship name such as Contacts.
+func (q *SOQL) Subquery(sub *SOQL) *SOQL {
+	s, err := sub.Build()
+	if err != nil && q.err == nil {
+		q.err = err
+	}
+	q.fields = append(q.fields, "("+s+")")
+	return q
+}
+
+func (q *SOQL) From(object string) *SOQL {
+	q.field(namePattern, object)
+	q.from = object
+	return q
+}
+
+// Where adds a condition. Conditions of successive calls are combined with
+// AND.
+func (q *SOQL) Where(c Condition) *SOQL {
+	q.where = append(q.where, c)
+	return q
+}
+
+func (q *SOQL) GroupBy(fields ...string) *SOQL {
+	for _, f := range fields {
+		q.field(fieldPattern, f)
+	}
+	q.groupBy = append(q.groupBy, fields...)
+	return q
+}
+
+// Having adds a condition on the groups. Conditions of successive calls are
+// combined with AND.
+func (q *SOQL) Having(c Condition) *SOQL {
+	q.having = append(q.having, c)
+	return q
+}
+
+func (q *SOQL) OrderBy(field string) *SOQL {
+	q.field(fieldPattern, field)
+	q.orderBy = append(q.orderBy, field+" ASC")
+	return q
+}
+
+func (q *SOQL) OrderByDesc(field string) *SOQL {
+	q.field(fieldPattern, field)
+	q.orderBy = append(q.orderBy, field+" DESC")
+	return q
+}
+
+func (q *SOQL) Limit(n int) *SOQL {
+	q.limit = n
+	return q
+}
+
+func (q *SOQL) Offset(n int) *SOQL {
+	q.offset = n
+	return q
+}
+
+// ForUpdate locks the returned records until the transaction ends.
+func (q *SOQL) ForUpdate() *SOQL {
+	q.forUpdate = true
+	return q
+}
+
+func (q *SOQL) field(pattern *regexp.Regexp, name string) {
+	if q.err == nil && !pattern.MatchString(name) {
+		q.err = fmt.Errorf("soapforce: invalid name in SOQL: %q", name)
+	}
+}
+
+// Build returns the query, or an error if a name is invalid or a value
+// can't be written as a SOQL literal.
+func (q *SOQL) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	if len(q.fields) == 0 {
+		return "", errors.New("soapforce: SOQL without fields")
+	}
+	if q.from == "" {
+		return "", errors.New("soapforce: SOQL without FROM")
+	}
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(strings.Join(q.fields, ", "))
+	b.WriteString(" FROM ")
+	b.WriteString(q.from)
+	if len(q.where) > 0 {
+		where, err := And(q.where...).soql()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(" WHERE ")
+		b.WriteString(where)
+	}
+	if len(q.groupBy) > 0 {
+		b.WriteString(" GROUP BY ")
+		b.WriteString(strings.Join(q.groupBy, ", "))
+	}
+	if len(q.having) > 0 {
+		having, err := And(q.having...).soql()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(" HAVING ")
+		b.WriteString(having)
+	}
+	if len(q.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	if q.limit > 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.Itoa(q.limit))
+	}
+	if q.offset > 0 {
+		b.WriteString(" OFFSET ")
+		b.WriteString(strconv.Itoa(q.offset))
+	}
+	if q.forUpdate {
+		b.WriteString(" FOR UPDATE")
+	}
+	return b.String(), nil
+}
+
+// String returns the query, or an empty string if Build fails.
+func (q *SOQL) String() string {
+	s, _ := q.Build()
+	return s
+}
+
+var (
+	namePattern  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	pathPattern  = `[A-Za-z][A-Za-z0-9_]*(?:\.[A-Za-z][A-Za-z0-9_]*)*`
+	fieldPattern = regexp.MustCompile(`^(?:` + pathPattern + `|[A-Za-z_]+\(\s*(?:` + pathPattern + `)?\s*\))$`)
+	// a field with an optional alias
+	selectPattern = regexp.MustCompile(`^(?:` + pathPattern + `|[A-Za-z_]+\(\s*(?:` + pathPattern + `)?\s*\))(?:\s+[A-Za-z][A-Za-z0-9_]*)?$`)
+)
+
+// Condition is a WHERE or HAVING expression.
+type Condition interface {
+	soql() (string, error)
+}
+
+type comparison struct {
+	field string
+	op    string
+	value interface{}
+}
+
+func (c *comparison) soql() (string, error) {
+	if !fieldPattern.MatchString(c.field) {
+		return "", fmt.Errorf("soapforce: invalid name in SOQL: %q", c.field)
+	}
+	var value string
+	var err error
+	switch c.op {
+	case "IN", "NOT IN", "INCLUDES", "EXCLUDES":
+		value, err = literalList(c.value)
+	case "LIKE":
+		value = likeLiteral(c.value.(string))
+	default:
+		value, err = Literal(c.value)
+	}
+	if err != nil {
+		return "", err
+	}
+	return c.field + " " + c.op + " " + value, nil
+}
+
+func Eq(field string, value interface{}) Condition {
+	return &comparison{field, "=", value}
+}
+
+func Ne(field string, value interface{}) Condition {
+	return &comparison{field, "!=", value}
+}
+
+func Lt(field string, value interface{}) Condition {
+	return &comparison{field, "<", value}
+}
+
+func Le(field string, value interface{}) Condition {
+	return &comparison{field, "<=", value}
+}
+
+func Gt(field string, value interface{}) Condition {
+	return &comparison{field, ">", value}
+}
+
+func Ge(field string, value interface{}) Condition {
+	return &comparison{field, ">=", value}
+}
+
+// Like matches a pattern in which % and _ are wildcards. Use EscapeLike to
+// match user input literally.
+func Like(field string, pattern string) Condition {
+	return &comparison{field, "LIKE", pattern}
+}
+
+// In matches any of values, which is a slice or a *SOQL semi-join.
+func In(field string, values interface{}) Condition {
+	return &comparison{field, "IN", values}
+}
+
+// NotIn matches none of values, which is a slice or a *SOQL anti-join.
+func NotIn(field string, values interface{}) Condition {
+	return &comparison{field, "NOT IN", values}
+}
+
+// Includes matches a multi-select picklist containing any of values.
+func Includes(field string, values ...string) Condition {
+	return &comparison{field, "INCLUDES", values}
+}
+
+// Excludes matches a multi-select picklist containing none of values.
+func Excludes(field string, values ...string) Condition {
+	return &comparison{field, "EXCLUDES", values}
+}
+
+type logical struct {
+	op    string
+	conds []Condition
+}
+
+func (l *logical) soql() (string, error) {
+	if len(l.conds) == 0 {
+		return "", fmt.Errorf("soapforce: %s without conditions in SOQL", l.op)
+	}
+	parts := make([]string, 0, len(l.conds))
+	for _, c := range l.conds {
+		s, err := c.soql()
+		if err != nil {
+			return "", err
+		}
+		if nested, ok := c.(*logical); ok && len(nested.conds) > 1 {
+			s = "(" + s + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " "+l.op+" "), nil
+}
+
+// And matches all of conds. Build fails if there are none, as there is no
+// SOQL for an empty group.
+func And(conds ...Condition) Condition {
+	return &logical{"AND", conds}
+}
+
+// Or matches any of conds. Build fails if there are none.
+func Or(conds ...Condition) Condition {
+	return &logical{"OR", conds}
+}
+
+type not struct {
+	cond Condition
+}
+
+func (n *not) soql() (string, error) {
+	s, err := n.cond.soql()
+	if err != nil {
+		return "", err
+	}
+	return "NOT (" + s + ")", nil
+}
+
+func Not(c Condition) Condition {
+	return &not{c}
+}
+
+// DateLiteral is a relative date such as TODAY or LAST_N_DAYS:30, written
+// as is.
+type DateLiteral string
+
+const (
+	Yesterday         DateLiteral = "YESTERDAY"
+	Today             DateLiteral = "TODAY"
+	Tomorrow          DateLiteral = "TOMORROW"
+	LastWeek          DateLiteral = "LAST_WEEK"
+	ThisWeek          DateLiteral = "THIS_WEEK"
+	NextWeek          DateLiteral = "NEXT_WEEK"
+	LastMonth         DateLiteral = "LAST_MONTH"
+	ThisMonth         DateLiteral = "THIS_MONTH"
+	NextMonth         DateLiteral = "NEXT_MONTH"
+	Last90Days        DateLiteral = "LAST_90_DAYS"
+	Next90Days        DateLiteral = "NEXT_90_DAYS"
+	ThisQuarter       DateLiteral = "THIS_QUARTER"
+	LastQuarter       DateLiteral = "LAST_QUARTER"
+	NextQuarter       DateLiteral = "NEXT_QUARTER"
+	ThisYear          DateLiteral = "THIS_YEAR"
+	LastYear          DateLiteral = "LAST_YEAR"
+	NextYear          DateLiteral = "NEXT_YEAR"
+	ThisFiscalYear    DateLiteral = "THIS_FISCAL_YEAR"
+	LastFiscalYear    DateLiteral = "LAST_FISCAL_YEAR"
+	NextFiscalYear    DateLiteral = "NEXT_FISCAL_YEAR"
+	ThisFiscalQuarter DateLiteral = "THIS_FISCAL_QUARTER"
+)
+
+func LastNDays(n int) DateLiteral   { return nDateLiteral("LAST_N_DAYS", n) }
+func NextNDays(n int) DateLiteral   { return nDateLiteral("NEXT_N_DAYS", n) }
+func NDaysAgo(n int) DateLiteral    { return nDateLiteral("N_DAYS_AGO", n) }
+func LastNWeeks(n int) DateLiteral  { return nDateLiteral("LAST_N_WEEKS", n) }
+func NextNWeeks(n int) DateLiteral  { return nDateLiteral("NEXT_N_WEEKS", n) }
+func LastNMonths(n int) DateLiteral { return nDateLiteral("LAST_N_MONTHS", n) }
+func NextNMonths(n int) DateLiteral { return nDateLiteral("NEXT_N_MONTHS", n) }
+func LastNYears(n int) DateLiteral  { return nDateLiteral("LAST_N_YEARS", n) }
+func NextNYears(n int) DateLiteral  { return nDateLiteral("NEXT_N_YEARS", n) }
+
+func nDateLiteral(name string, n int) DateLiteral {
+	return DateLiteral(name + ":" + strconv.Itoa(n))
+}
+
+var dateLiteralPattern = regexp.MustCompile(`^[A-Z_0-9]+(?::\d+)?$`)
+
+// Literal writes v as a SOQL literal: strings are quoted and escaped,
+// time.Time is a dateTime, Date a date, and nil is null.
+func Literal(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return "'" + EscapeString(v) + "'", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.UTC().Format("2006-01-02T15:04:05Z"), nil
+	case Date:
+		return v.String(), nil
+	case DateLiteral:
+		if !dateLiteralPattern.MatchString(string(v)) {
+			return "", fmt.Errorf("soapforce: invalid date literal: %q", string(v))
+		}
+		return string(v), nil
+	case *SOQL:
+		s, err := v.Build()
+		if err != nil {
+			return "", err
+		}
+		return "(" + s + ")", nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "null", nil
+		}
+		return Literal(rv.Elem().Interface())
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return Literal(rv.String())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return formatFieldValue(v)
+	}
+	return "", fmt.Errorf("soapforce: cannot write %T as a SOQL literal", v)
+}
+
+// literalList writes a slice as a parenthesized list of literals. A *SOQL
+// is written as a subquery.
+func literalList(v interface{}) (string, error) {
+	if _, ok := v.(*SOQL); ok {
+		return Literal(v)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("soapforce: IN needs a slice or a subquery, not %T", v)
+	}
+	if rv.Len() == 0 {
+		return "", errors.New("soapforce: IN with an empty list")
+	}
+	items := make([]string, rv.Len())
+	for i := range items {
+		s, err := Literal(rv.Index(i).Interface())
+		if err != nil {
+			return "", err
+		}
+		items[i] = s
+	}
+	return "(" + strings.Join(items, ", ") + ")", nil
+}
+
+var soqlEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\b", `\b`,
+	"\f", `\f`,
+)
+
+// EscapeString escapes s for use inside a quoted SOQL string literal.
+func EscapeString(s string) string {
+	return soqlEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeLiteral quotes a LIKE pattern. The escapes \%, \_ and \\ are kept,
+// any other backslash is escaped.
+func likeLiteral(pattern string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '\\' {
+			continue
+		}
+		b.WriteString(EscapeString(pattern[start:i]))
+		if i+1 < len(pattern) && strings.IndexByte(`%_\`, pattern[i+1]) >= 0 {
+			b.WriteString(pattern[i : i+2])
+			i++
+		} else {
+			b.WriteString(`\\`)
+		}
+		start = i + 1
+	}
+	b.WriteString(EscapeString(pattern[start:]))
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// EscapeLike escapes the wildcards of s, so that Like matches it literally,
+// e.g. Like("Name", "%"+EscapeLike(input)+"%").
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
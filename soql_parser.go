@@ -0,0 +1,755 @@
+package soapforce
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParsedQuery is the syntax tree of a SOQL query.
+type ParsedQuery struct {
+	Pos
+	Fields    []Expr
+	From      *FieldExpr
+	FromAlias string
+	Where     Expr
+	With      []string
+	GroupBy   []Expr
+	Having    Expr
+	OrderBy   []*OrderByItem
+	Limit     *int
+	Offset    *int
+	// For is VIEW, REFERENCE or UPDATE.
+	For string
+}
+
+// Pos is the 1-based position of a node in the query.
+type Pos struct {
+	Row    int
+	Column int
+}
+
+func (p Pos) Position() Pos {
+	return p
+}
+
+// Expr is a node of a SELECT list, condition or ORDER BY.
+type Expr interface {
+	Position() Pos
+}
+
+// FieldExpr is a field or relationship path, e.g. Account.Owner.Name.
+type FieldExpr struct {
+	Pos
+	Path []string
+}
+
+func (f *FieldExpr) Name() string {
+	return strings.Join(f.Path, ".")
+}
+
+// FuncExpr is a function call, e.g. COUNT(Id) or toLabel(Status).
+type FuncExpr struct {
+	Pos
+	Name  string
+	Args  []Expr
+	Alias string
+}
+
+type LiteralKind int
+
+const (
+	LiteralString LiteralKind = iota
+	LiteralNumber
+	LiteralBoolean
+	LiteralNull
+	LiteralDate
+	LiteralDateTime
+	// LiteralSymbol is a date literal such as LAST_N_DAYS:30 or a currency
+	// value such as USD5000.
+	LiteralSymbol
+	// LiteralBind is a bind variable such as :accountId, as in Apex
+	// queries. Value is its name.
+	LiteralBind
+)
+
+type LiteralExpr struct {
+	Pos
+	Kind LiteralKind
+	// Value is the unescaped value of a string.
+	Value string
+}
+
+// ListExpr is the value list of IN, NOT IN, INCLUDES and EXCLUDES.
+type ListExpr struct {
+	Pos
+	Items []Expr
+}
+
+// SubqueryExpr is a child relationship query in a SELECT list or a
+// semi-join in a condition.
+type SubqueryExpr struct {
+	Pos
+	Query *ParsedQuery
+}
+
+// TypeOfExpr is a polymorphic TYPEOF field. Its WHEN clauses are not kept.
+type TypeOfExpr struct {
+	Pos
+	Field *FieldExpr
+}
+
+type ComparisonExpr struct {
+	Pos
+	Left Expr
+	// Op is one of = != < <= > >= LIKE IN NOT IN INCLUDES EXCLUDES.
+	Op    string
+	Right Expr
+}
+
+// LogicalExpr is an AND or OR of two conditions.
+type LogicalExpr struct {
+	Pos
+	Op          string
+	Left, Right Expr
+}
+
+type NotExpr struct {
+	Pos
+	X Expr
+}
+
+type OrderByItem struct {
+	Expr  Expr
+	Desc  bool
+	Nulls string
+}
+
+// ParseSOQL parses a query. Syntax errors are returned as a
+// *MalformedQueryFault with the row and column of the error, like the ones
+// returned by the server.
+func ParseSOQL(soql string) (*ParsedQuery, error) {
+	tokens, err := lexSOQL(soql)
+	if err != nil {
+		return nil, err
+	}
+	p := &soqlParser{tokens: tokens}
+	q, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.unexpected(t)
+	}
+	return q, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDate
+	tokDateTime
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokColon
+)
+
+type token struct {
+	Pos
+	kind tokenKind
+	text string
+}
+
+var (
+	dateTimeTokenPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})`)
+	dateTokenPattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+	numberTokenPattern   = regexp.MustCompile(`^[+-]?\d+(?:\.\d+)?`)
+)
+
+func lexSOQL(s string) ([]token, error) {
+	var tokens []token
+	row, col := 1, 1
+	advance := func(n int) {
+		for _, r := range s[:n] {
+			if r == '\n' {
+				row, col = row+1, 1
+			} else {
+				col++
+			}
+		}
+		s = s[n:]
+	}
+	for {
+		for len(s) > 0 && unicode.IsSpace(rune(s[0])) {
+			advance(1)
+		}
+		pos := Pos{Row: row, Column: col}
+		if len(s) == 0 {
+			return append(tokens, token{Pos: pos, kind: tokEOF}), nil
+		}
+		c := s[0]
+		switch {
+		case c == '\'':
+			text, n, ok := scanString(s)
+			if !ok {
+				return nil, newQueryFault(ExceptionCodeMALFORMED_QUERY, pos, "unterminated string")
+			}
+			tokens = append(tokens, token{Pos: pos, kind: tokString, text: text})
+			advance(n)
+		case c == '(':
+			tokens = append(tokens, token{Pos: pos, kind: tokLParen, text: "("})
+			advance(1)
+		case c == ')':
+			tokens = append(tokens, token{Pos: pos, kind: tokRParen, text: ")"})
+			advance(1)
+		case c == ',':
+			tokens = append(tokens, token{Pos: pos, kind: tokComma, text: ","})
+			advance(1)
+		case c == ':':
+			tokens = append(tokens, token{Pos: pos, kind: tokColon, text: ":"})
+			advance(1)
+		case c == '=' || c == '<' || c == '>' || c == '!':
+			op := string(c)
+			if len(s) > 1 && (s[1] == '=' || (c == '<' && s[1] == '>')) {
+				op = s[:2]
+			}
+			if op == "!" {
+				return nil, newQueryFault(ExceptionCodeMALFORMED_QUERY, pos, "unexpected token: '!'")
+			}
+			tokens = append(tokens, token{Pos: pos, kind: tokOp, text: op})
+			advance(len(op))
+		case dateTimeTokenPattern.MatchString(s):
+			m := dateTimeTokenPattern.FindString(s)
+			tokens = append(tokens, token{Pos: pos, kind: tokDateTime, text: m})
+			advance(len(m))
+		case dateTokenPattern.MatchString(s):
+			m := dateTokenPattern.FindString(s)
+			tokens = append(tokens, token{Pos: pos, kind: tokDate, text: m})
+			advance(len(m))
+		case numberTokenPattern.MatchString(s):
+			m := numberTokenPattern.FindString(s)
+			tokens = append(tokens, token{Pos: pos, kind: tokNumber, text: m})
+			advance(len(m))
+		case c == '_' || unicode.IsLetter(rune(c)):
+			n := 1
+			for n < len(s) && (s[n] == '_' || s[n] == '.' || unicode.IsLetter(rune(s[n])) || unicode.IsDigit(rune(s[n]))) {
+				n++
+			}
+			tokens = append(tokens, token{Pos: pos, kind: tokIdent, text: s[:n]})
+			advance(n)
+		default:
+			return nil, newQueryFault(ExceptionCodeMALFORMED_QUERY, pos, "unexpected token: '%c'", c)
+		}
+	}
+}
+
+// scanString reads a quoted string literal and returns its value and
+// length. The LIKE escapes \% and \_ are kept.
+func scanString(s string) (string, int, bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			return b.String(), i + 1, true
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", 0, false
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '%', '_':
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, false
+}
+
+type soqlParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *soqlParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *soqlParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *soqlParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *soqlParser) acceptKeyword(kw string) bool {
+	if p.isKeyword(kw) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *soqlParser) expectKeyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *soqlParser) expect(kind tokenKind) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.unexpected(t)
+	}
+	return t, nil
+}
+
+func (p *soqlParser) unexpected(t token) error {
+	if t.kind == tokEOF {
+		return newQueryFault(ExceptionCodeMALFORMED_QUERY, t.Pos, "unexpected end of query")
+	}
+	return newQueryFault(ExceptionCodeMALFORMED_QUERY, t.Pos, "unexpected token: '%s'", t.text)
+}
+
+// clauseKeywords end a FROM alias or a SELECT alias.
+var clauseKeywords = map[string]bool{
+	"FROM": true, "WHERE": true, "WITH": true, "GROUP": true, "HAVING": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "FOR": true, "UPDATE": true, "USING": true, "ALL": true,
+}
+
+func (p *soqlParser) isAlias() bool {
+	t := p.peek()
+	return t.kind == tokIdent && !clauseKeywords[strings.ToUpper(t.text)]
+}
+
+func (p *soqlParser) query() (*ParsedQuery, error) {
+	q := &ParsedQuery{Pos: p.peek().Pos}
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := p.selectItem()
+		if err != nil {
+			return nil, err
+		}
+		q.Fields = append(q.Fields, f)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	from, err := p.expect(tokIdent)
+	if err != nil {
+		return nil, err
+	}
+	q.From = &FieldExpr{Pos: from.Pos, Path: strings.Split(from.text, ".")}
+	if p.isAlias() {
+		q.FromAlias = p.next().text
+	}
+	if p.acceptKeyword("USING") {
+		if err := p.expectKeyword("SCOPE"); err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokIdent); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("WHERE") {
+		if q.Where, err = p.condition(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("WITH") {
+		for p.peek().kind == tokIdent && !clauseKeywords[strings.ToUpper(p.peek().text)] {
+			q.With = append(q.With, p.next().text)
+		}
+	}
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			q.GroupBy = append(q.GroupBy, e)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.acceptKeyword("HAVING") {
+		if q.Having, err = p.condition(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			item := &OrderByItem{Expr: e}
+			if p.acceptKeyword("DESC") {
+				item.Desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			if p.acceptKeyword("NULLS") {
+				t, err := p.expect(tokIdent)
+				if err != nil {
+					return nil, err
+				}
+				item.Nulls = strings.ToUpper(t.text)
+				if item.Nulls != "FIRST" && item.Nulls != "LAST" {
+					return nil, p.unexpected(t)
+				}
+			}
+			q.OrderBy = append(q.OrderBy, item)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if q.Limit, err = p.integer(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("OFFSET") {
+		if q.Offset, err = p.integer(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("FOR") {
+		t, err := p.expect(tokIdent)
+		if err != nil {
+			return nil, err
+		}
+		q.For = strings.ToUpper(t.text)
+		if q.For != "VIEW" && q.For != "REFERENCE" && q.For != "UPDATE" {
+			return nil, p.unexpected(t)
+		}
+	}
+	if p.acceptKeyword("UPDATE") {
+		if _, err := p.expect(tokIdent); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ALL") {
+		if err := p.expectKeyword("ROWS"); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+func (p *soqlParser) integer() (*int, error) {
+	t, err := p.expect(tokNumber)
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(t.text)
+	if err != nil || n < 0 {
+		return nil, p.unexpected(t)
+	}
+	return &n, nil
+}
+
+func (p *soqlParser) selectItem() (Expr, error) {
+	t := p.peek()
+	if t.kind == tokLParen {
+		p.next()
+		sub, err := p.query()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return &SubqueryExpr{Pos: t.Pos, Query: sub}, nil
+	}
+	if p.isKeyword("TYPEOF") {
+		p.next()
+		field, err := p.expect(tokIdent)
+		if err != nil {
+			return nil, err
+		}
+		for !p.isKeyword("END") {
+			if p.peek().kind == tokEOF {
+				return nil, p.unexpected(p.peek())
+			}
+			p.next()
+		}
+		p.next()
+		return &TypeOfExpr{Pos: t.Pos, Field: &FieldExpr{Pos: field.Pos, Path: strings.Split(field.text, ".")}}, nil
+	}
+	e, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := e.(*FuncExpr); ok && p.isAlias() {
+		f.Alias = p.next().text
+	}
+	return e, nil
+}
+
+// operand parses a field or a function call.
+func (p *soqlParser) operand() (Expr, error) {
+	t, err := p.expect(tokIdent)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokLParen {
+		return &FieldExpr{Pos: t.Pos, Path: strings.Split(t.text, ".")}, nil
+	}
+	p.next()
+	f := &FuncExpr{Pos: t.Pos, Name: t.text}
+	for p.peek().kind != tokRParen {
+		var arg Expr
+		if p.peek().kind == tokIdent {
+			arg, err = p.operand()
+		} else {
+			arg, err = p.literal()
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.Args = append(f.Args, arg)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *soqlParser) condition() (Expr, error) {
+	left, err := p.andCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		t := p.next()
+		right, err := p.andCondition()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Pos: t.Pos, Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *soqlParser) andCondition() (Expr, error) {
+	left, err := p.notCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		t := p.next()
+		right, err := p.notCondition()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Pos: t.Pos, Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *soqlParser) notCondition() (Expr, error) {
+	if p.isKeyword("NOT") {
+		t := p.next()
+		x, err := p.notCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Pos: t.Pos, X: x}, nil
+	}
+	if p.peek().kind == tokLParen {
+		p.next()
+		c, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return p.comparison()
+}
+
+func (p *soqlParser) comparison() (Expr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.next()
+	c := &ComparisonExpr{Pos: t.Pos, Left: left}
+	switch {
+	case t.kind == tokOp:
+		c.Op = t.text
+		if c.Op == "<>" {
+			c.Op = "!="
+		}
+		c.Right, err = p.literal()
+		return c, err
+	case t.kind == tokIdent && strings.EqualFold(t.text, "LIKE"):
+		c.Op = "LIKE"
+		c.Right, err = p.literal()
+		return c, err
+	case t.kind == tokIdent && strings.EqualFold(t.text, "NOT"):
+		if err := p.expectKeyword("IN"); err != nil {
+			return nil, err
+		}
+		c.Op = "NOT IN"
+	case t.kind == tokIdent && (strings.EqualFold(t.text, "IN") || strings.EqualFold(t.text, "INCLUDES") || strings.EqualFold(t.text, "EXCLUDES")):
+		c.Op = strings.ToUpper(t.text)
+	default:
+		return nil, p.unexpected(t)
+	}
+	c.Right, err = p.list()
+	return c, err
+}
+
+// list parses a parenthesized list of literals, a semi-join or a bind
+// variable.
+func (p *soqlParser) list() (Expr, error) {
+	if p.peek().kind == tokColon {
+		return p.literal()
+	}
+	open, err := p.expect(tokLParen)
+	if err != nil {
+		return nil, err
+	}
+	if p.isKeyword("SELECT") {
+		sub, err := p.query()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return &SubqueryExpr{Pos: open.Pos, Query: sub}, nil
+	}
+	l := &ListExpr{Pos: open.Pos}
+	for {
+		item, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		l.Items = append(l.Items, item)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (p *soqlParser) literal() (Expr, error) {
+	t := p.next()
+	l := &LiteralExpr{Pos: t.Pos, Value: t.text}
+	switch t.kind {
+	case tokString:
+		l.Kind = LiteralString
+	case tokNumber:
+		l.Kind = LiteralNumber
+	case tokDate:
+		l.Kind = LiteralDate
+	case tokDateTime:
+		l.Kind = LiteralDateTime
+	case tokColon:
+		name, err := p.expect(tokIdent)
+		if err != nil {
+			return nil, err
+		}
+		l.Kind = LiteralBind
+		l.Value = name.text
+	case tokIdent:
+		switch strings.ToUpper(t.text) {
+		case "TRUE", "FALSE":
+			l.Kind = LiteralBoolean
+		case "NULL":
+			l.Kind = LiteralNull
+		default:
+			l.Kind = LiteralSymbol
+			if p.peek().kind == tokColon {
+				p.next()
+				n, err := p.expect(tokNumber)
+				if err != nil {
+					return nil, err
+				}
+				l.Value += ":" + n.text
+			}
+		}
+	default:
+		return nil, p.unexpected(t)
+	}
+	return l, nil
+}
+
+// newQueryFault returns the typed fault the server would return for code,
+// with the position in the message as in server responses.
+func newQueryFault(code ExceptionCode, pos Pos, format string, args ...interface{}) error {
+	message := fmt.Sprintf("ERROR at Row:%d:Column:%d\n", pos.Row, pos.Column) + fmt.Sprintf(format, args...)
+	fault := &ApiQueryFault{
+		ApiFault: &ApiFault{ExceptionCode: &code, ExceptionMessage: message},
+		Row:      int32(pos.Row),
+		Column:   int32(pos.Column),
+	}
+	switch code {
+	case ExceptionCodeMALFORMED_QUERY:
+		return &MalformedQueryFault{ApiQueryFault: fault}
+	case ExceptionCodeINVALID_FIELD:
+		return &InvalidFieldFault{ApiQueryFault: fault}
+	case ExceptionCodeINVALID_TYPE:
+		return &InvalidSObjectFault{ApiQueryFault: fault}
+	}
+	return fault
+}
@@ -0,0 +1,176 @@
+package soapforce
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSOQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		soql  string
+		check func(t *testing.T, q *ParsedQuery)
+	}{
+		{
+			name: "child subquery",
+			soql: "SELECT Id, Name, (SELECT Id, LastName FROM Contacts WHERE LastName LIKE 'S%' ORDER BY LastName) FROM Account a",
+			check: func(t *testing.T, q *ParsedQuery) {
+				if len(q.Fields) != 3 || q.From.Name() != "Account" || q.FromAlias != "a" {
+					t.Fatalf("got %d fields from %s %s", len(q.Fields), q.From.Name(), q.FromAlias)
+				}
+				sub, ok := q.Fields[2].(*SubqueryExpr)
+				if !ok {
+					t.Fatalf("third field is %T, want *SubqueryExpr", q.Fields[2])
+				}
+				if sub.Query.From.Name() != "Contacts" || len(sub.Query.OrderBy) != 1 {
+					t.Errorf("subquery from %s with %d ORDER BY items", sub.Query.From.Name(), len(sub.Query.OrderBy))
+				}
+				if c, ok := sub.Query.Where.(*ComparisonExpr); !ok || c.Op != "LIKE" {
+					t.Errorf("subquery WHERE is %#v, want a LIKE comparison", sub.Query.Where)
+				}
+			},
+		},
+		{
+			name: "semi-join",
+			soql: "SELECT Id FROM Account WHERE Id IN (SELECT AccountId FROM Contact WHERE Email != null)",
+			check: func(t *testing.T, q *ParsedQuery) {
+				c := q.Where.(*ComparisonExpr)
+				if _, ok := c.Right.(*SubqueryExpr); c.Op != "IN" || !ok {
+					t.Errorf("got %s %T, want IN with a subquery", c.Op, c.Right)
+				}
+			},
+		},
+		{
+			name: "group by and having",
+			soql: "SELECT Industry, COUNT(Id) cnt FROM Account GROUP BY Industry HAVING COUNT(Id) > 1 AND MAX(AnnualRevenue) < 1000000",
+			check: func(t *testing.T, q *ParsedQuery) {
+				f, ok := q.Fields[1].(*FuncExpr)
+				if !ok || f.Name != "COUNT" || f.Alias != "cnt" {
+					t.Errorf("second field is %#v, want COUNT(Id) cnt", q.Fields[1])
+				}
+				if len(q.GroupBy) != 1 {
+					t.Errorf("got %d GROUP BY items", len(q.GroupBy))
+				}
+				if l, ok := q.Having.(*LogicalExpr); !ok || l.Op != "AND" {
+					t.Errorf("HAVING is %#v, want an AND", q.Having)
+				}
+			},
+		},
+		{
+			name: "date literals",
+			soql: "SELECT Id FROM Opportunity WHERE CloseDate = LAST_N_DAYS:30 OR CloseDate < 2021-01-01 OR CreatedDate > 2020-01-01T00:00:00Z OR CloseDate = TODAY",
+			check: func(t *testing.T, q *ParsedQuery) {
+				var kinds []LiteralKind
+				var values []string
+				var walk func(e Expr)
+				walk = func(e Expr) {
+					switch e := e.(type) {
+					case *LogicalExpr:
+						walk(e.Left)
+						walk(e.Right)
+					case *ComparisonExpr:
+						l := e.Right.(*LiteralExpr)
+						kinds = append(kinds, l.Kind)
+						values = append(values, l.Value)
+					}
+				}
+				walk(q.Where)
+				wantKinds := []LiteralKind{LiteralSymbol, LiteralDate, LiteralDateTime, LiteralSymbol}
+				wantValues := []string{"LAST_N_DAYS:30", "2021-01-01", "2020-01-01T00:00:00Z", "TODAY"}
+				for i := range wantKinds {
+					if i >= len(kinds) || kinds[i] != wantKinds[i] || values[i] != wantValues[i] {
+						t.Fatalf("got literals %v %v, want %v %v", kinds, values, wantKinds, wantValues)
+					}
+				}
+			},
+		},
+		{
+			name: "bind variables",
+			soql: "SELECT Id FROM Account WHERE Id = :accountId AND Name IN :names",
+			check: func(t *testing.T, q *ParsedQuery) {
+				l := q.Where.(*LogicalExpr)
+				for _, e := range []Expr{l.Left, l.Right} {
+					v, ok := e.(*ComparisonExpr).Right.(*LiteralExpr)
+					if !ok || v.Kind != LiteralBind {
+						t.Errorf("got %#v, want a bind variable", e.(*ComparisonExpr).Right)
+					}
+				}
+				if v := l.Right.(*ComparisonExpr).Right.(*LiteralExpr).Value; v != "names" {
+					t.Errorf("bind variable is %q, want names", v)
+				}
+			},
+		},
+		{
+			name: "order by nulls",
+			soql: "SELECT Id FROM Account ORDER BY Name DESC NULLS LAST, CreatedDate NULLS FIRST LIMIT 10 OFFSET 5",
+			check: func(t *testing.T, q *ParsedQuery) {
+				if len(q.OrderBy) != 2 {
+					t.Fatalf("got %d ORDER BY items", len(q.OrderBy))
+				}
+				if o := q.OrderBy[0]; !o.Desc || o.Nulls != "LAST" {
+					t.Errorf("first item is desc=%v nulls=%q", o.Desc, o.Nulls)
+				}
+				if o := q.OrderBy[1]; o.Desc || o.Nulls != "FIRST" {
+					t.Errorf("second item is desc=%v nulls=%q", o.Desc, o.Nulls)
+				}
+				if q.Limit == nil || *q.Limit != 10 || q.Offset == nil || *q.Offset != 5 {
+					t.Errorf("got LIMIT %v OFFSET %v", q.Limit, q.Offset)
+				}
+			},
+		},
+		{
+			name: "escaped string and not",
+			soql: `SELECT Id FROM Account WHERE Name = 'O\'Brien' AND NOT (Type = null OR Type <> 'Customer')`,
+			check: func(t *testing.T, q *ParsedQuery) {
+				l := q.Where.(*LogicalExpr)
+				if v := l.Left.(*ComparisonExpr).Right.(*LiteralExpr).Value; v != "O'Brien" {
+					t.Errorf("string is %q, want O'Brien", v)
+				}
+				if _, ok := l.Right.(*NotExpr); !ok {
+					t.Errorf("got %T, want *NotExpr", l.Right)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseSOQL(tt.soql)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, q)
+		})
+	}
+}
+
+func TestParseSOQLMalformed(t *testing.T) {
+	tests := []struct {
+		soql        string
+		row, column int
+	}{
+		{"SELECT Id FROM", 1, 15},
+		{"SELECT Id FROM Account WHERE", 1, 29},
+		{"SELECT Id FROM Account WHERE Name ! 'x'", 1, 35},
+		{"SELECT Id FROM Account\nWHERE Name = = 'x'", 2, 14},
+		{"SELECT Id,\n  Name FROM Account WHERE Name = 'abc", 2, 34},
+		{"SELECT Id FROM Account LIMIT x", 1, 30},
+		{"SELECT Id FROM Account WHERE Id IN ()", 1, 37},
+		{"SELECT Id FROM Account WHERE Id = :", 1, 36},
+		{"SELECT Id, (SELECT Id FROM Contacts FROM Account", 1, 37},
+		{"SELECT Id FROM Account ORDER BY Name NULLS", 1, 43},
+	}
+	for _, tt := range tests {
+		_, err := ParseSOQL(tt.soql)
+		var fault *MalformedQueryFault
+		if !errors.As(err, &fault) {
+			t.Errorf("%q: got %v, want a *MalformedQueryFault", tt.soql, err)
+			continue
+		}
+		if int(fault.Row) != tt.row || int(fault.Column) != tt.column {
+			t.Errorf("%q: error at row %d column %d, want row %d column %d: %v", tt.soql, fault.Row, fault.Column, tt.row, tt.column, err)
+		}
+		if !errors.Is(err, ExceptionCodeMALFORMED_QUERY) {
+			t.Errorf("%q: errors.Is(err, MALFORMED_QUERY) = false", tt.soql)
+		}
+	}
+}
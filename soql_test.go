@@ -0,0 +1,54 @@
+package soapforce
+
+import "testing"
+
+func TestSOQLBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *SOQL
+		want string
+	}{
+		{
+			"nested groups",
+			Select("Id").From("Account").
+				Where(And(Eq("Name", "O'Brien"), Or(Gt("NumberOfEmployees", 10), Eq("Type", nil)))),
+			`SELECT Id FROM Account WHERE (Name = 'O\'Brien' AND (NumberOfEmployees > 10 OR Type = null))`,
+		},
+		{
+			"having",
+			Select("Industry", "COUNT(Id) cnt").From("Account").GroupBy("Industry").
+				Having(Gt("COUNT(Id)", 1)).Having(Or(Lt("COUNT(Id)", 100))),
+			`SELECT Industry, COUNT(Id) cnt FROM Account GROUP BY Industry HAVING COUNT(Id) > 1 AND COUNT(Id) < 100`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.q.Build()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSOQLEmptyLogicalGroup(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *SOQL
+	}{
+		{"empty and", Select("Id").From("Account").Where(And())},
+		{"empty or", Select("Id").From("Account").Where(Or())},
+		{"nested empty group", Select("Id").From("Account").Where(And(Eq("Name", "Acme"), Or()))},
+		{"nested empty groups", Select("Id").From("Account").Where(Or(And(), And(Or())))},
+		{"empty not", Select("Id").From("Account").Where(Not(And()))},
+		{"empty having", Select("Industry").From("Account").GroupBy("Industry").Having(And())},
+		{"nested empty having", Select("Industry").From("Account").GroupBy("Industry").Having(Or(Gt("COUNT(Id)", 1), And()))},
+	}
+	for _, tt := range tests {
+		if got, err := tt.q.Build(); err == nil {
+			t.Errorf("%s: Build() = %q, want an error", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package soapforce
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SOQLValidator checks queries against describe results without calling
+// the server. Errors are the typed faults the server would return, e.g.
+// *InvalidFieldFault for an unknown field, with the row and column of the
+// offending token.
+//
+// Relationships to objects that were not described, and polymorphic
+// relationships, are not checked past the relationship name.
+type SOQLValidator struct {
+	objects map[string]*sobjectSchema
+}
+
+type sobjectSchema struct {
+	name          string
+	fields        map[string]*Field
+	relationships map[string]*Field
+	children      map[string]*ChildRelationship
+}
+
+func NewSOQLValidator(results ...*DescribeSObjectResult) *SOQLValidator {
+	v := &SOQLValidator{objects: map[string]*sobjectSchema{}}
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		s := &sobjectSchema{
+			name:          r.Name,
+			fields:        map[string]*Field{},
+			relationships: map[string]*Field{},
+			children:      map[string]*ChildRelationship{},
+		}
+		for _, f := range r.Fields {
+			s.fields[strings.ToLower(f.Name)] = f
+			if f.RelationshipName != "" {
+				s.relationships[strings.ToLower(f.RelationshipName)] = f
+			}
+		}
+		for _, c := range r.ChildRelationships {
+			if c.RelationshipName != "" {
+				s.children[strings.ToLower(c.RelationshipName)] = c
+			}
+		}
+		v.objects[strings.ToLower(r.Name)] = s
+	}
+	return v
+}
+
+// Validate parses and checks soql. It returns the first error found.
+func (v *SOQLValidator) Validate(soql string) error {
+	q, err := ParseSOQL(soql)
+	if err != nil {
+		return err
+	}
+	return v.ValidateQuery(q)
+}
+
+func (v *SOQLValidator) ValidateQuery(q *ParsedQuery) error {
+	return v.query(q, nil)
+}
+
+// query checks q. parent is the object of the enclosing query when q is a
+// child relationship subquery.
+func (v *SOQLValidator) query(q *ParsedQuery, parent *sobjectSchema) error {
+	var obj *sobjectSchema
+	if parent == nil {
+		obj = v.objects[strings.ToLower(q.From.Name())]
+		if obj == nil {
+			return newQueryFault(ExceptionCodeINVALID_TYPE, q.From.Pos, "sObject type '%s' is not supported.", q.From.Name())
+		}
+	} else {
+		rel := parent.children[strings.ToLower(q.From.Name())]
+		if rel == nil {
+			return newQueryFault(ExceptionCodeINVALID_TYPE, q.From.Pos, "Didn't understand relationship '%s' in FROM part of query call.", q.From.Name())
+		}
+		if obj = v.objects[strings.ToLower(rel.ChildSObject)]; obj == nil {
+			return nil
+		}
+	}
+	c := &queryChecker{validator: v, object: obj, alias: q.FromAlias}
+
+	for _, f := range q.Fields {
+		if sub, ok := f.(*SubqueryExpr); ok {
+			if parent != nil {
+				return newQueryFault(ExceptionCodeMALFORMED_QUERY, sub.Pos, "nested subqueries are not supported")
+			}
+			if err := v.query(sub.Query, obj); err != nil {
+				return err
+			}
+			continue
+		}
+		if t, ok := f.(*TypeOfExpr); ok {
+			if _, err := c.relationship(t.Field); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := c.operand(f, ""); err != nil {
+			return err
+		}
+	}
+	if err := c.condition(q.Where, "filtered"); err != nil {
+		return err
+	}
+	for _, e := range q.GroupBy {
+		if _, err := c.operand(e, "grouped"); err != nil {
+			return err
+		}
+	}
+	// aliases of the select list, e.g. cnt in COUNT(Id) cnt, can be used
+	// from HAVING on
+	c.aliases = map[string]bool{}
+	for _, f := range q.Fields {
+		if fn, ok := f.(*FuncExpr); ok && fn.Alias != "" {
+			c.aliases[strings.ToLower(fn.Alias)] = true
+		}
+	}
+	if err := c.condition(q.Having, ""); err != nil {
+		return err
+	}
+	for _, o := range q.OrderBy {
+		if _, err := c.operand(o.Expr, "sorted"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type queryChecker struct {
+	validator *SOQLValidator
+	object    *sobjectSchema
+	alias     string
+	aliases   map[string]bool
+}
+
+// operand checks a field or function and returns the field, or nil when it
+// is unknown, a function or the alias of one. usage is the clause the field is used in:
+// "filtered", "grouped" or "sorted".
+func (c *queryChecker) operand(e Expr, usage string) (*Field, error) {
+	switch e := e.(type) {
+	case *FieldExpr:
+		if len(e.Path) == 1 && c.aliases[strings.ToLower(e.Path[0])] {
+			return nil, nil
+		}
+		f, err := c.field(e)
+		if err != nil || f == nil {
+			return nil, err
+		}
+		if (usage == "filtered" && !f.Filterable) || (usage == "grouped" && !f.Groupable) || (usage == "sorted" && !f.Sortable) {
+			return nil, newQueryFault(ExceptionCodeINVALID_FIELD, e.Pos, "field '%s' can not be %s in a query call", f.Name, usage)
+		}
+		return f, nil
+	case *FuncExpr:
+		// aggregates such as COUNT(Id) can be sorted and filtered on fields
+		// that can't
+		if isAggregateFunc(e.Name) {
+			usage = ""
+		}
+		for _, arg := range e.Args {
+			if _, err := c.operand(arg, usage); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+func isAggregateFunc(name string) bool {
+	switch strings.ToUpper(name) {
+	case "COUNT", "COUNT_DISTINCT", "SUM", "AVG", "MIN", "MAX":
+		return true
+	}
+	return false
+}
+
+// path returns the path of e without the FROM alias.
+func (c *queryChecker) path(e *FieldExpr) []string {
+	if len(e.Path) > 1 && c.alias != "" && strings.EqualFold(e.Path[0], c.alias) {
+		return e.Path[1:]
+	}
+	return e.Path
+}
+
+// relationship follows the relationships of the path of e, up to the last
+// name. It returns nil when the object can't be checked.
+func (c *queryChecker) relationship(e *FieldExpr) (*sobjectSchema, error) {
+	obj := c.object
+	path := c.path(e)
+	for _, name := range path[:len(path)-1] {
+		rel := obj.relationships[strings.ToLower(name)]
+		if rel == nil {
+			return nil, newQueryFault(ExceptionCodeINVALID_FIELD, e.Pos, "Didn't understand relationship '%s' in field path.", name)
+		}
+		if len(rel.ReferenceTo) != 1 {
+			return nil, nil
+		}
+		if obj = c.validator.objects[strings.ToLower(rel.ReferenceTo[0])]; obj == nil {
+			return nil, nil
+		}
+	}
+	return obj, nil
+}
+
+func (c *queryChecker) field(e *FieldExpr) (*Field, error) {
+	obj, err := c.relationship(e)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	path := c.path(e)
+	name := path[len(path)-1]
+	f := obj.fields[strings.ToLower(name)]
+	if f == nil {
+		return nil, newQueryFault(ExceptionCodeINVALID_FIELD, e.Pos, "No such column '%s' on entity '%s'.", name, obj.name)
+	}
+	return f, nil
+}
+
+func (c *queryChecker) condition(e Expr, usage string) error {
+	switch e := e.(type) {
+	case *LogicalExpr:
+		if err := c.condition(e.Left, usage); err != nil {
+			return err
+		}
+		return c.condition(e.Right, usage)
+	case *NotExpr:
+		return c.condition(e.X, usage)
+	case *ComparisonExpr:
+		f, err := c.operand(e.Left, usage)
+		if err != nil {
+			return err
+		}
+		if sub, ok := e.Right.(*SubqueryExpr); ok {
+			return c.validator.query(sub.Query, nil)
+		}
+		if f == nil {
+			return nil
+		}
+		return checkComparison(f, e)
+	}
+	return nil
+}
+
+var currencyLiteralPattern = regexp.MustCompile(`^[A-Za-z]{3}-?\d+(?:\.\d+)?$`)
+
+// checkComparison checks that the operator and values of e fit the type of
+// f.
+func checkComparison(f *Field, e *ComparisonExpr) error {
+	var fieldType FieldType
+	if f.Type_ != nil {
+		fieldType = *f.Type_
+	}
+	switch e.Op {
+	case "LIKE":
+		// unlike the other operators, LIKE doesn't take ids
+		if f.SoapType == nil || localType(string(*f.SoapType)) != "string" {
+			return newQueryFault(ExceptionCodeINVALID_QUERY_FILTER_OPERATOR, e.Pos, "invalid operator on %s field: LIKE", fieldType)
+		}
+	case "INCLUDES", "EXCLUDES":
+		if fieldType != FieldTypeMultipicklist {
+			return newQueryFault(ExceptionCodeINVALID_QUERY_FILTER_OPERATOR, e.Pos, "%s operator is only valid on multipicklist fields", e.Op)
+		}
+	}
+	values := []Expr{e.Right}
+	if l, ok := e.Right.(*ListExpr); ok {
+		values = l.Items
+	}
+	for _, v := range values {
+		l, ok := v.(*LiteralExpr)
+		if !ok || l.Kind == LiteralNull || l.Kind == LiteralBind || literalFits(f, l) {
+			continue
+		}
+		quoted := "should not be enclosed in quotes"
+		if soapTypeName(f) == "string" {
+			quoted = "should be enclosed in quotes"
+		}
+		return newQueryFault(ExceptionCodeINVALID_FIELD, l.Pos, "value of filter criterion for field '%s' must be of type %s and %s", f.Name, soapTypeName(f), quoted)
+	}
+	return nil
+}
+
+// soapTypeName returns the type of f as named in filter errors.
+func soapTypeName(f *Field) string {
+	if f.SoapType == nil {
+		return ""
+	}
+	switch t := localType(string(*f.SoapType)); t {
+	case "ID":
+		return "string"
+	default:
+		return t
+	}
+}
+
+func literalFits(f *Field, l *LiteralExpr) bool {
+	switch soapTypeName(f) {
+	case "string":
+		return l.Kind == LiteralString
+	case "boolean":
+		return l.Kind == LiteralBoolean
+	case "int", "long", "double":
+		return l.Kind == LiteralNumber ||
+			(l.Kind == LiteralSymbol && f.Type_ != nil && *f.Type_ == FieldTypeCurrency && currencyLiteralPattern.MatchString(l.Value))
+	case "date":
+		return l.Kind == LiteralDate || (l.Kind == LiteralSymbol && dateLiteralPattern.MatchString(strings.ToUpper(l.Value)))
+	case "dateTime":
+		return l.Kind == LiteralDateTime || (l.Kind == LiteralSymbol && dateLiteralPattern.MatchString(strings.ToUpper(l.Value)))
+	}
+	return true
+}
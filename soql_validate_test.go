@@ -0,0 +1,130 @@
+package soapforce
+
+import (
+	"errors"
+	"testing"
+)
+
+func testField(name string, soapType SoapType, fieldType FieldType, filterable, groupable, sortable bool) *Field {
+	return &Field{
+		Name:       name,
+		SoapType:   &soapType,
+		Type_:      &fieldType,
+		Filterable: filterable,
+		Groupable:  groupable,
+		Sortable:   sortable,
+	}
+}
+
+func testValidator() *SOQLValidator {
+	owner := testField("OwnerId", "tns:ID", FieldTypeReference, true, true, true)
+	owner.RelationshipName = "Owner"
+	owner.ReferenceTo = []string{"User"}
+	account := &DescribeSObjectResult{
+		Name: "Account",
+		Fields: []*Field{
+			testField("Id", "tns:ID", FieldTypeId, true, true, true),
+			testField("Name", "xsd:string", FieldTypeString, true, true, true),
+			testField("Description", "xsd:string", FieldTypeTextarea, false, false, false),
+			testField("AnnualRevenue", "xsd:double", FieldTypeCurrency, true, false, true),
+			testField("NumberOfEmployees", "xsd:int", FieldTypeInt, true, true, true),
+			testField("IsDeleted", "xsd:boolean", FieldTypeBoolean, true, true, true),
+			testField("CreatedDate", "xsd:dateTime", FieldTypeDatetime, true, false, true),
+			testField("Regions__c", "xsd:string", FieldTypeMultipicklist, true, false, false),
+			owner,
+		},
+		ChildRelationships: []*ChildRelationship{{ChildSObject: "Contact", RelationshipName: "Contacts"}},
+	}
+	user := &DescribeSObjectResult{
+		Name:   "User",
+		Fields: []*Field{testField("Name", "xsd:string", FieldTypeString, true, true, true)},
+	}
+	contact := &DescribeSObjectResult{
+		Name: "Contact",
+		Fields: []*Field{
+			testField("Id", "tns:ID", FieldTypeId, true, true, true),
+			testField("LastName", "xsd:string", FieldTypeString, true, true, true),
+			testField("Birthdate", "xsd:date", FieldTypeDate, true, true, true),
+		},
+	}
+	return NewSOQLValidator(account, user, contact)
+}
+
+func TestSOQLValidatorValid(t *testing.T) {
+	v := testValidator()
+	for _, soql := range []string{
+		"SELECT Id, Name, Owner.Name, (SELECT LastName FROM Contacts WHERE Birthdate = LAST_N_DAYS:30) FROM Account",
+		"SELECT a.Name FROM Account a WHERE a.NumberOfEmployees > 10 ORDER BY a.Name NULLS LAST",
+		"SELECT Name, COUNT(Id) FROM Account GROUP BY Name HAVING COUNT(Id) > 1 ORDER BY COUNT(Id) DESC",
+		"SELECT Id FROM Account WHERE AnnualRevenue > USD5000 AND IsDeleted = false AND CreatedDate > 2020-01-01T00:00:00Z",
+		"SELECT Id FROM Account WHERE Regions__c INCLUDES ('EMEA', 'APAC') AND Name LIKE 'Acme%'",
+		"SELECT Id FROM Account WHERE Id = :accountId AND NumberOfEmployees IN :sizes",
+		"SELECT Id FROM Account WHERE Id IN (SELECT Id FROM Contact WHERE LastName = 'Smith')",
+		"SELECT Name, COUNT(Id) cnt FROM Account GROUP BY Name HAVING cnt > 1 ORDER BY cnt DESC",
+		"SELECT Name, MAX(AnnualRevenue) Revenue FROM Account GROUP BY Name ORDER BY revenue",
+	} {
+		if err := v.Validate(soql); err != nil {
+			t.Errorf("%q: %v", soql, err)
+		}
+	}
+}
+
+func TestSOQLValidatorInvalid(t *testing.T) {
+	v := testValidator()
+	tests := []struct {
+		soql        string
+		code        ExceptionCode
+		row, column int
+	}{
+		// unknown names
+		{"SELECT Id FROM Acount", ExceptionCodeINVALID_TYPE, 1, 16},
+		{"SELECT Id, Nmae FROM Account", ExceptionCodeINVALID_FIELD, 1, 12},
+		{"SELECT Id, Ownr.Name FROM Account", ExceptionCodeINVALID_FIELD, 1, 12},
+		{"SELECT Id, (SELECT Id FROM Cases) FROM Account", ExceptionCodeINVALID_TYPE, 1, 28},
+		// non-filterable, non-groupable and non-sortable fields
+		{"SELECT Id FROM Account WHERE Description = 'x'", ExceptionCodeINVALID_FIELD, 1, 30},
+		{"SELECT AnnualRevenue FROM Account GROUP BY AnnualRevenue", ExceptionCodeINVALID_FIELD, 1, 44},
+		{"SELECT Id FROM Account ORDER BY Regions__c", ExceptionCodeINVALID_FIELD, 1, 33},
+		{"SELECT Id FROM Account ORDER BY Description DESC", ExceptionCodeINVALID_FIELD, 1, 33},
+		// type mismatches
+		{"SELECT Id FROM Account WHERE NumberOfEmployees > '10'", ExceptionCodeINVALID_FIELD, 1, 50},
+		{"SELECT Id FROM Account WHERE Name = 10", ExceptionCodeINVALID_FIELD, 1, 37},
+		{"SELECT Id FROM Account WHERE IsDeleted = 'false'", ExceptionCodeINVALID_FIELD, 1, 42},
+		{"SELECT Id FROM Account WHERE CreatedDate > 2020-01-01", ExceptionCodeINVALID_FIELD, 1, 44},
+		{"SELECT Id FROM Account WHERE Name IN ('a', 2)", ExceptionCodeINVALID_FIELD, 1, 44},
+		{"SELECT Id FROM Account WHERE NumberOfEmployees LIKE '1%'", ExceptionCodeINVALID_QUERY_FILTER_OPERATOR, 1, 48},
+		{"SELECT Id FROM Account WHERE Name INCLUDES ('a')", ExceptionCodeINVALID_QUERY_FILTER_OPERATOR, 1, 35},
+		{"SELECT Id FROM Account WHERE Id LIKE '001%'", ExceptionCodeINVALID_QUERY_FILTER_OPERATOR, 1, 33},
+		{"SELECT Id FROM Account WHERE OwnerId LIKE '005%'", ExceptionCodeINVALID_QUERY_FILTER_OPERATOR, 1, 38},
+		// aliases are only known from HAVING on, and only name aggregates
+		{"SELECT Name, COUNT(Id) cnt FROM Account WHERE cnt > 1 GROUP BY Name", ExceptionCodeINVALID_FIELD, 1, 47},
+		{"SELECT Name, COUNT(Id) cnt FROM Account GROUP BY Name ORDER BY total", ExceptionCodeINVALID_FIELD, 1, 64},
+	}
+	for _, tt := range tests {
+		err := v.Validate(tt.soql)
+		if !errors.Is(err, tt.code) {
+			t.Errorf("%q: got %v, want %s", tt.soql, err, tt.code)
+			continue
+		}
+		var fault *ApiQueryFault
+		if !errors.As(err, &fault) {
+			t.Errorf("%q: %T is not an *ApiQueryFault", tt.soql, err)
+			continue
+		}
+		if int(fault.Row) != tt.row || int(fault.Column) != tt.column {
+			t.Errorf("%q: error at row %d column %d, want row %d column %d: %v", tt.soql, fault.Row, fault.Column, tt.row, tt.column, err)
+		}
+	}
+}
+
+func TestSOQLValidatorFaultTypes(t *testing.T) {
+	v := testValidator()
+	var fieldFault *InvalidFieldFault
+	if err := v.Validate("SELECT Nmae FROM Account"); !errors.As(err, &fieldFault) {
+		t.Errorf("unknown field: got %T, want *InvalidFieldFault", err)
+	}
+	var sobjectFault *InvalidSObjectFault
+	if err := v.Validate("SELECT Id FROM Acount"); !errors.As(err, &sobjectFault) {
+		t.Errorf("unknown object: got %T, want *InvalidSObjectFault", err)
+	}
+}
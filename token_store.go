@@ -0,0 +1,337 @@
+package soapforce
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TokenStore persists OAuth tokens so that a session survives a restart,
+// see Client.SetTokenStore.
+type TokenStore interface {
+	// Load returns ErrTokenNotFound if no token was saved for key.
+	Load(ctx context.Context, key TokenKey) (*OAuthToken, error)
+	Save(ctx context.Context, key TokenKey, token *OAuthToken) error
+	Delete(ctx context.Context, key TokenKey) error
+}
+
+// TokenKey identifies a saved token, e.g. by org id or login URL, and
+// username.
+type TokenKey struct {
+	Org  string
+	User string
+}
+
+var ErrTokenNotFound = errors.New("soapforce: token not found")
+
+// ErrWrongPassphrase is returned by FileTokenStore.Load when a token can't
+// be decrypted.
+var ErrWrongPassphrase = errors.New("soapforce: wrong passphrase or corrupted token file")
+
+// FileTokenStore saves each token in a file of Dir, readable by the owner
+// only. Tokens are encrypted with AES-GCM when Passphrase is set.
+type FileTokenStore struct {
+	Dir        string
+	Passphrase string
+}
+
+func NewFileTokenStore(dir, passphrase string) *FileTokenStore {
+	return &FileTokenStore{Dir: dir, Passphrase: passphrase}
+}
+
+// tokenFile is the content of a token file: Token, or Data with the
+// encrypted token.
+type tokenFile struct {
+	Token      *OAuthToken `json:"token,omitempty"`
+	Salt       []byte      `json:"salt,omitempty"`
+	Iterations int         `json:"iterations,omitempty"`
+	Nonce      []byte      `json:"nonce,omitempty"`
+	Data       []byte      `json:"data,omitempty"`
+}
+
+const tokenKeyIterations = 100000
+
+func (s *FileTokenStore) path(key TokenKey) string {
+	sum := sha256.Sum256([]byte(key.Org + "\x00" + key.User))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FileTokenStore) Load(ctx context.Context, key TokenKey) (*OAuthToken, error) {
+	b, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f tokenFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("soapforce: reading token file: %v", err)
+	}
+	if f.Data == nil {
+		if f.Token == nil {
+			return nil, ErrTokenNotFound
+		}
+		return f.Token, nil
+	}
+	if s.Passphrase == "" {
+		return nil, errors.New("soapforce: token file is encrypted but no passphrase is set")
+	}
+	gcm, err := tokenCipher(s.Passphrase, f.Salt, f.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	token := &OAuthToken{}
+	if err := json.Unmarshal(plain, token); err != nil {
+		return nil, fmt.Errorf("soapforce: reading token file: %v", err)
+	}
+	return token, nil
+}
+
+func (s *FileTokenStore) Save(ctx context.Context, key TokenKey, token *OAuthToken) error {
+	f := tokenFile{Token: token}
+	if s.Passphrase != "" {
+		plain, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		f = tokenFile{Salt: make([]byte, 16), Iterations: tokenKeyIterations}
+		if _, err := rand.Read(f.Salt); err != nil {
+			return err
+		}
+		gcm, err := tokenCipher(s.Passphrase, f.Salt, f.Iterations)
+		if err != nil {
+			return err
+		}
+		f.Nonce = make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(f.Nonce); err != nil {
+			return err
+		}
+		f.Data = gcm.Seal(nil, f.Nonce, plain, nil)
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first so that a crash leaves the old token
+	tmp, err := ioutil.TempFile(s.Dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+func (s *FileTokenStore) Delete(ctx context.Context, key TokenKey) error {
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func tokenCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 {
+		return nil, ErrWrongPassphrase
+	}
+	block, err := aes.NewCipher(pbkdf2SHA256([]byte(passphrase), salt, iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key from password as specified in RFC 8018.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	var counter [4]byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// SetTokenStore makes the client save the tokens of its OAuth logins and
+// refreshes under key, and lets RestoreSession load them.
+func (c *Client) SetTokenStore(store TokenStore, key TokenKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokenStore = store
+	c.tokenKey = key
+}
+
+// WithTokenStore sets a token store, see SetTokenStore.
+func WithTokenStore(store TokenStore, key TokenKey) ClientOption {
+	return func(c *Client) {
+		c.SetTokenStore(store, key)
+	}
+}
+
+func (c *Client) tokenStoreSettings() (TokenStore, TokenKey) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tokenStore, c.tokenKey
+}
+
+// saveToken saves token in the token store, if any.
+func (c *Client) saveToken(ctx context.Context, token *OAuthToken) error {
+	store, key := c.tokenStoreSettings()
+	if store == nil {
+		return nil
+	}
+	if err := store.Save(ctx, key, token); err != nil {
+		return fmt.Errorf("soapforce: saving token: %v", err)
+	}
+	return nil
+}
+
+// deleteToken deletes the token saved in the token store, if any.
+func (c *Client) deleteToken(ctx context.Context) error {
+	store, key := c.tokenStoreSettings()
+	if store == nil {
+		return nil
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("soapforce: deleting token: %v", err)
+	}
+	return nil
+}
+
+// deleteStoredRefreshToken deletes the saved token if its refresh token is
+// refreshToken.
+func (c *Client) deleteStoredRefreshToken(ctx context.Context, refreshToken string) error {
+	store, key := c.tokenStoreSettings()
+	if store == nil {
+		return nil
+	}
+	token, err := store.Load(ctx, key)
+	if err != nil || token.RefreshToken != refreshToken {
+		return nil
+	}
+	return c.deleteToken(ctx)
+}
+
+// checkStoredToken checks that a saved token can be used by the client: it
+// must have an access token, and have been issued by the login host of the
+// client for an instance on the same scheme.
+func (c *Client) checkStoredToken(token *OAuthToken) error {
+	if token.AccessToken == "" {
+		return errors.New("soapforce: saved token has no access token")
+	}
+	loginUrl := loginBaseUrl(c.settings().LoginUrl)
+	if token.LoginUrl != loginUrl {
+		return fmt.Errorf("soapforce: saved token was issued by %q, not %q", token.LoginUrl, loginUrl)
+	}
+	instance, err := url.Parse(token.InstanceUrl)
+	if err != nil || instance.Host == "" || !strings.HasPrefix(loginUrl, instance.Scheme+"://") {
+		return fmt.Errorf("soapforce: saved token has an invalid instance URL %q", token.InstanceUrl)
+	}
+	return nil
+}
+
+// RestoreSession switches the client to the session saved in its token
+// store. The session is checked with GetUserInfo; if it has expired, it is
+// refreshed with the saved refresh token and the new token is saved. A saved
+// token that has expired and can't be refreshed is deleted.
+func (c *Client) RestoreSession() (*OAuthToken, error) {
+	return c.RestoreSessionContext(context.Background())
+}
+
+func (c *Client) RestoreSessionContext(ctx context.Context) (*OAuthToken, error) {
+	store, key := c.tokenStoreSettings()
+	if store == nil {
+		return nil, errors.New("soapforce: no token store set")
+	}
+	token, err := store.Load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkStoredToken(token); err != nil {
+		return nil, err
+	}
+	c.setToken(token)
+	info, err := c.GetUserInfoContext(ctx)
+	if err == nil {
+		c.mu.Lock()
+		c.UserInfo = info
+		c.mu.Unlock()
+		return token, nil
+	}
+	if !errors.Is(err, ExceptionCodeINVALID_SESSION_ID) {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		if derr := c.deleteToken(ctx); derr != nil {
+			return nil, fmt.Errorf("%v; %v", err, derr)
+		}
+		return nil, err
+	}
+	return c.RefreshContext(ctx, token.RefreshToken)
+}
+
+// StoredTokenCredentials re-authenticates with the refresh token saved in
+// the token store of the client.
+func StoredTokenCredentials() CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context, c *Client) error {
+		store, key := c.tokenStoreSettings()
+		if store == nil {
+			return errors.New("soapforce: no token store set")
+		}
+		token, err := store.Load(ctx, key)
+		if err != nil {
+			return err
+		}
+		if token.RefreshToken == "" {
+			return errors.New("soapforce: saved token has no refresh token")
+		}
+		_, err = c.RefreshContext(ctx, token.RefreshToken)
+		return err
+	})
+}
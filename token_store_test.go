@@ -0,0 +1,263 @@
+package soapforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testTokenKey = TokenKey{Org: "00Dxx", User: "user@example.com"}
+
+func testToken() *OAuthToken {
+	return &OAuthToken{
+		AccessToken:  "00Dxx!token",
+		RefreshToken: "refresh",
+		InstanceUrl:  "https://example.my.salesforce.com",
+		Id:           "https://login.salesforce.com/id/00Dxx/005xx",
+		LoginUrl:     "https://login.salesforce.com",
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	for _, passphrase := range []string{"", "passphrase"} {
+		dir, err := ioutil.TempDir("", "soapforce")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		ctx := context.Background()
+		store := NewFileTokenStore(filepath.Join(dir, "tokens"), passphrase)
+		if err := store.Save(ctx, testTokenKey, testToken()); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ioutil.ReadDir(store.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Mode().Perm() != 0600 {
+			t.Fatalf("passphrase=%q: files %v, want one file with mode 0600", passphrase, files)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(store.Dir, files[0].Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted := !bytes.Contains(b, []byte("00Dxx!token")); encrypted != (passphrase != "") {
+			t.Errorf("passphrase=%q: token file %s", passphrase, b)
+		}
+
+		token, err := store.Load(ctx, testTokenKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *token != *testToken() {
+			t.Errorf("passphrase=%q: got %+v, want %+v", passphrase, token, testToken())
+		}
+		if _, err := store.Load(ctx, TokenKey{Org: "00Dxx", User: "other@example.com"}); err != ErrTokenNotFound {
+			t.Errorf("passphrase=%q: got %v for another key, want ErrTokenNotFound", passphrase, err)
+		}
+
+		if err := store.Delete(ctx, testTokenKey); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Load(ctx, testTokenKey); err != ErrTokenNotFound {
+			t.Errorf("passphrase=%q: got %v after Delete, want ErrTokenNotFound", passphrase, err)
+		}
+		if err := store.Delete(ctx, testTokenKey); err != nil {
+			t.Errorf("passphrase=%q: deleting a missing token: %v", passphrase, err)
+		}
+	}
+}
+
+func TestFileTokenStoreWrongPassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "soapforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	if err := NewFileTokenStore(dir, "passphrase").Save(ctx, testTokenKey, testToken()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileTokenStore(dir, "other").Load(ctx, testTokenKey); err != ErrWrongPassphrase {
+		t.Errorf("got %v, want ErrWrongPassphrase", err)
+	}
+	if _, err := NewFileTokenStore(dir, "").Load(ctx, testTokenKey); err == nil {
+		t.Error("encrypted token loaded without a passphrase")
+	}
+}
+
+func TestFileTokenStoreCorruptFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "soapforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	store := NewFileTokenStore(dir, "passphrase")
+	path := store.path(testTokenKey)
+
+	if err := ioutil.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := store.Load(ctx, testTokenKey); err == nil {
+		t.Errorf("got %+v from a file that is not JSON", token)
+	}
+
+	if err := store.Save(ctx, testTokenKey, testToken()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f tokenFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		t.Fatal(err)
+	}
+	f.Data[0] ^= 1
+	if b, err = json.Marshal(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, testTokenKey); err != ErrWrongPassphrase {
+		t.Errorf("got %v for a modified token, want ErrWrongPassphrase", err)
+	}
+}
+
+// newStoreClient returns a client that saves its token in a store of dir
+// with token.
+func newStoreClient(t *testing.T, dir, url string, token *OAuthToken) (*Client, TokenStore) {
+	store := NewFileTokenStore(dir, "passphrase")
+	if err := store.Save(context.Background(), testTokenKey, token); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(url)
+	c.SetTokenStore(store, testTokenKey)
+	return c, store
+}
+
+func TestLogoutDeletesToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "soapforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newSOAPServer(t, func(string) string { return "<logoutResponse/>" })
+	defer server.Close()
+
+	c, store := newStoreClient(t, dir, server.URL, testToken())
+	if err := c.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(context.Background(), testTokenKey); err != ErrTokenNotFound {
+		t.Errorf("got %v after Logout, want ErrTokenNotFound", err)
+	}
+}
+
+func TestRefreshInvalidGrantDeletesToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_grant","error_description":"expired access/refresh token"}`)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "soapforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		refreshToken string
+		deleted      bool
+	}{
+		{"refresh", true},
+		// another token than the saved one was rejected
+		{"other", false},
+	} {
+		c, store := newStoreClient(t, dir, server.URL, testToken())
+		c.SetLoginUrl(server.URL)
+		if _, err := c.Refresh(tt.refreshToken); err == nil {
+			t.Fatalf("%s: Refresh succeeded, want invalid_grant", tt.refreshToken)
+		}
+		_, err := store.Load(context.Background(), testTokenKey)
+		if deleted := err == ErrTokenNotFound; deleted != tt.deleted {
+			t.Errorf("%s: got %v, want the token deleted=%v", tt.refreshToken, err, tt.deleted)
+		}
+	}
+}
+
+func TestRestoreSessionChecksToken(t *testing.T) {
+	server := newSOAPServer(t, func(request string) string {
+		t.Errorf("saved token used: %s", request)
+		return ""
+	})
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "soapforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name   string
+		modify func(*OAuthToken)
+	}{
+		{"no access token", func(token *OAuthToken) { token.AccessToken = "" }},
+		{"other login host", func(token *OAuthToken) { token.LoginUrl = "https://test.salesforce.com" }},
+		{"no login host", func(token *OAuthToken) { token.LoginUrl = "" }},
+		{"http instance", func(token *OAuthToken) { token.InstanceUrl = "http://example.my.salesforce.com" }},
+		{"relative instance", func(token *OAuthToken) { token.InstanceUrl = "/services" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := testToken()
+			tt.modify(token)
+			c, _ := newStoreClient(t, dir, server.URL, token)
+			c.SetLoginUrl("login.salesforce.com")
+			if _, err := c.RestoreSession(); err == nil {
+				t.Fatal("RestoreSession succeeded, want the token rejected")
+			}
+			if c.Token() != nil {
+				t.Errorf("token %+v installed", c.Token())
+			}
+		})
+	}
+}
+
+func TestRestoreSessionDeletesExpiredToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "soapforce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := newSOAPServer(t, func(string) string { return invalidSessionFaultXML })
+	defer server.Close()
+
+	token := testToken()
+	token.RefreshToken = ""
+	token.InstanceUrl = server.URL
+	token.LoginUrl = server.URL
+	c, store := newStoreClient(t, dir, server.URL, token)
+	c.SetLoginUrl(server.URL)
+	if _, err := c.RestoreSession(); err == nil {
+		t.Fatal("RestoreSession succeeded, want INVALID_SESSION_ID")
+	}
+	if _, err := store.Load(context.Background(), testTokenKey); err != ErrTokenNotFound {
+		t.Errorf("got %v, want the expired token deleted", err)
+	}
+}
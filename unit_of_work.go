@@ -0,0 +1,305 @@
+package soapforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UnitOfWork collects records to create, update and delete, and references
+// between them, and saves them with as few calls as the dependencies allow.
+//
+//	uow := client.NewUnitOfWork()
+//	account := &soapforce.SObject{Type: "Account", Fields: map[string]interface{}{"Name": "Acme"}}
+//	contact := &soapforce.SObject{Type: "Contact", Fields: map[string]interface{}{"LastName": "Smith"}}
+//	uow.RegisterNew(account, contact)
+//	uow.RegisterRelationship(contact, "AccountId", account)
+//	err := uow.Commit()
+//
+// Records are created parents first, one Create per object type and level of
+// the dependency graph, then updated, then deleted. Each call is all-or-none;
+// a call of more than 200 records is split into chunks, see ChunkSize, and
+// each chunk is all-or-none on its own. If a call fails, the records created
+// by the commit are deleted and the ones it deleted are undeleted, even if
+// the context was canceled; updates are not reverted.
+//
+// A UnitOfWork is not safe for concurrent use.
+type UnitOfWork struct {
+	client     *Client
+	created    []*SObject
+	isNew      map[*SObject]bool
+	updated    []*SObject
+	deleted    []string
+	references map[*SObject][]reference
+}
+
+type reference struct {
+	field  string
+	parent *SObject
+}
+
+func (c *Client) NewUnitOfWork() *UnitOfWork {
+	u := &UnitOfWork{client: c}
+	u.reset()
+	return u
+}
+
+func (u *UnitOfWork) reset() {
+	u.created = nil
+	u.isNew = map[*SObject]bool{}
+	u.updated = nil
+	u.deleted = nil
+	u.references = map[*SObject][]reference{}
+}
+
+// RegisterNew adds records to create.
+func (u *UnitOfWork) RegisterNew(records ...*SObject) {
+	for _, r := range records {
+		if r != nil && !u.isNew[r] {
+			u.isNew[r] = true
+			u.created = append(u.created, r)
+		}
+	}
+}
+
+// RegisterDirty adds records to update.
+func (u *UnitOfWork) RegisterDirty(records ...*SObject) {
+	for _, r := range records {
+		if r != nil {
+			u.updated = append(u.updated, r)
+		}
+	}
+}
+
+// RegisterDeleted adds the ids of records to delete.
+func (u *UnitOfWork) RegisterDeleted(ids ...string) {
+	u.deleted = append(u.deleted, ids...)
+}
+
+// RegisterRelationship sets field of record to the Id of parent when parent
+// has been created. record is created after parent if both are new.
+func (u *UnitOfWork) RegisterRelationship(record *SObject, field string, parent *SObject) {
+	u.references[record] = append(u.references[record], reference{field: field, parent: parent})
+}
+
+// UnitOfWorkError is returned by Commit when a call failed.
+type UnitOfWorkError struct {
+	// Step is the failed call, e.g. "create Contact", "update" or "delete".
+	Step string
+	Err  error
+	// CompensationErr is set when the records created or deleted by the
+	// commit could not all be deleted or undeleted.
+	CompensationErr error
+}
+
+func (e *UnitOfWorkError) Error() string {
+	msg := fmt.Sprintf("soapforce: unit of work: %s: %v", e.Step, e.Err)
+	if e.CompensationErr != nil {
+		msg += fmt.Sprintf("; rolling back: %v", e.CompensationErr)
+	}
+	return msg
+}
+
+func (e *UnitOfWorkError) Unwrap() error {
+	return e.Err
+}
+
+// Commit saves the registered records. The Ids of the created records are
+// set, and the unit of work is emptied when it succeeds.
+func (u *UnitOfWork) Commit(opts ...CallOption) error {
+	return u.CommitContext(context.Background(), opts...)
+}
+
+func (u *UnitOfWork) CommitContext(ctx context.Context, opts ...CallOption) error {
+	steps, err := u.createSteps()
+	if err != nil {
+		return err
+	}
+	ctx = WithCallOptions(ctx, append([]CallOption{AllOrNone(true)}, opts...)...)
+
+	var inserted []*SObject
+	var deleted []string
+	fail := func(step string, err error) error {
+		// the commit may have failed because ctx is done
+		cctx, cancel := context.WithTimeout(detachedContext{ctx}, compensationTimeout)
+		defer cancel()
+		return &UnitOfWorkError{Step: step, Err: err, CompensationErr: u.compensate(cctx, inserted, deleted)}
+	}
+	for _, records := range steps {
+		for _, r := range records {
+			u.fillReferences(r)
+		}
+		res, err := u.client.CreateContext(ctx, records)
+		batch := NewSaveBatchResult(records, res, err)
+		for _, s := range batch.Succeeded {
+			s.Record.Id = s.Id
+			inserted = append(inserted, s.Record)
+		}
+		if err := batch.Err(); err != nil {
+			return fail("create "+records[0].Type, err)
+		}
+	}
+
+	if len(u.updated) > 0 {
+		for _, r := range u.updated {
+			u.fillReferences(r)
+		}
+		res, err := u.client.UpdateContext(ctx, u.updated)
+		if err := NewSaveBatchResult(u.updated, res, err).Err(); err != nil {
+			return fail("update", err)
+		}
+	}
+
+	if len(u.deleted) > 0 {
+		res, err := u.client.DeleteContext(ctx, u.deleted)
+		batch := NewDeleteBatchResult(u.deleted, res, err)
+		for _, s := range batch.Succeeded {
+			deleted = append(deleted, s.Id)
+		}
+		if err := batch.Err(); err != nil {
+			return fail("delete", err)
+		}
+	}
+	u.reset()
+	return nil
+}
+
+// createSteps groups the new records into Create calls: by level of the
+// dependency graph, then by type in the order they were registered.
+func (u *UnitOfWork) createSteps() ([][]*SObject, error) {
+	const (
+		visiting = -1
+		unknown  = 0
+	)
+	// levels are stored plus one, so that zero means not visited yet
+	levels := map[*SObject]int{}
+	var level func(r *SObject) (int, error)
+	level = func(r *SObject) (int, error) {
+		switch levels[r] {
+		case visiting:
+			return 0, fmt.Errorf("soapforce: unit of work: circular reference involving %s record", r.Type)
+		case unknown:
+		default:
+			return levels[r] - 1, nil
+		}
+		levels[r] = visiting
+		l := 0
+		for _, ref := range u.references[r] {
+			if !u.isNew[ref.parent] {
+				continue
+			}
+			pl, err := level(ref.parent)
+			if err != nil {
+				return 0, err
+			}
+			if pl+1 > l {
+				l = pl + 1
+			}
+		}
+		levels[r] = l + 1
+		return l, nil
+	}
+
+	maxLevel := 0
+	for _, r := range u.created {
+		l, err := level(r)
+		if err != nil {
+			return nil, err
+		}
+		if l > maxLevel {
+			maxLevel = l
+		}
+	}
+	var steps [][]*SObject
+	for l := 0; l <= maxLevel; l++ {
+		index := map[string]int{}
+		for _, r := range u.created {
+			if levels[r]-1 != l {
+				continue
+			}
+			i, ok := index[r.Type]
+			if !ok {
+				i = len(steps)
+				index[r.Type] = i
+				steps = append(steps, nil)
+			}
+			steps[i] = append(steps[i], r)
+		}
+	}
+	return steps, nil
+}
+
+func (u *UnitOfWork) fillReferences(r *SObject) {
+	for _, ref := range u.references[r] {
+		if ref.parent.Id == "" {
+			continue
+		}
+		if r.Fields == nil {
+			r.Fields = map[string]interface{}{}
+		}
+		r.Fields[ref.field] = ref.parent.Id
+	}
+}
+
+// compensationTimeout bounds the calls that roll back a failed commit.
+const compensationTimeout = 2 * time.Minute
+
+// detachedContext keeps the values of a context, such as its call options,
+// without its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// compensate deletes the inserted records, children first, and undeletes
+// the deleted ones.
+func (u *UnitOfWork) compensate(ctx context.Context, inserted []*SObject, deleted []string) error {
+	ctx = WithCallOptions(ctx, AllOrNone(false))
+	var errs []error
+	if len(deleted) > 0 {
+		res, err := u.client.UndeleteContext(ctx, deleted)
+		if err := NewUndeleteBatchResult(deleted, res, err).Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(inserted) > 0 {
+		ids := make([]string, len(inserted))
+		for i, r := range inserted {
+			ids[len(inserted)-1-i] = r.Id
+		}
+		res, err := u.client.DeleteContext(ctx, ids)
+		batch := NewDeleteBatchResult(ids, res, err)
+		var failed []*RecordFailure
+		remaining := map[string]bool{}
+		for _, f := range batch.Failed {
+			// children deleted along with their parent
+			if !errors.Is(f, StatusCodeENTITY_IS_DELETED) {
+				failed = append(failed, f)
+				remaining[f.Id] = true
+			}
+		}
+		if len(failed) > 0 {
+			errs = append(errs, &BatchError{Failures: failed, Total: len(ids)})
+		}
+		for _, r := range inserted {
+			if !remaining[r.Id] {
+				r.Id = ""
+			}
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return fmt.Errorf("%v; %v", errs[0], errs[1])
+}
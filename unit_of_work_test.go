@@ -0,0 +1,174 @@
+package soapforce
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	sobjectTypePattern = regexp.MustCompile(`<sObjects[^>]*><type>(\w+)</type>`)
+	idsPattern         = regexp.MustCompile(`<ids>([^<]*)</ids>`)
+)
+
+// unitOfWorkServer records the calls it gets as "create Account",
+// "delete id1,id2", ... Created records get ids made of their type and a
+// counter. fail tells whether a call fails with INVALID_FIELD, and deleteFails
+// which ids fail to delete.
+type unitOfWorkServer struct {
+	t           *testing.T
+	mu          sync.Mutex
+	calls       []string
+	ids         int
+	fail        func(call string) bool
+	deleteFails map[string]bool
+	// compensating is set once a call failed
+	compensating bool
+}
+
+func (s *unitOfWorkServer) respond(request string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	allOrNone := strings.Contains(request, "<allOrNone>true</allOrNone>")
+	var call, body string
+	switch {
+	case strings.Contains(request, "<create"):
+		types := sobjectTypePattern.FindAllStringSubmatch(request, -1)
+		call = "create " + types[0][1]
+		for _, m := range types {
+			s.ids++
+			body += fmt.Sprintf("<result><id>%s-%d</id><success>true</success></result>", m[1], s.ids)
+		}
+		body = "<createResponse>" + body + "</createResponse>"
+	case strings.Contains(request, "<update"):
+		call = "update"
+		body = "<updateResponse>" + strings.Repeat(saveResultXML, strings.Count(request, "<sObjects")) + "</updateResponse>"
+	case strings.Contains(request, "<delete"), strings.Contains(request, "<undelete"):
+		op := "delete"
+		if strings.Contains(request, "<undelete") {
+			op = "undelete"
+		}
+		var ids []string
+		for _, m := range idsPattern.FindAllStringSubmatch(request, -1) {
+			ids = append(ids, m[1])
+			if op == "delete" && s.deleteFails[m[1]] {
+				body += "<result><errors><statusCode>ENTITY_IS_LOCKED</statusCode></errors><id>" + m[1] + "</id><success>false</success></result>"
+			} else {
+				body += "<result><id>" + m[1] + "</id><success>true</success></result>"
+			}
+		}
+		call = op + " " + strings.Join(ids, ",")
+		body = "<" + op + "Response>" + body + "</" + op + "Response>"
+	default:
+		s.t.Errorf("unexpected request: %s", request)
+		return ""
+	}
+	// only the commit is all-or-none, not its compensation
+	if allOrNone == s.compensating {
+		s.t.Errorf("%s: allOrNone=%v", call, allOrNone)
+	}
+	s.calls = append(s.calls, call)
+	if strings.Contains(body, "<success>false</success>") {
+		s.compensating = true
+	}
+	if s.fail != nil && s.fail(call) {
+		s.compensating = true
+		return ""
+	}
+	return body
+}
+
+func TestUnitOfWorkCommit(t *testing.T) {
+	uowServer := &unitOfWorkServer{t: t}
+	server := newSOAPServer(t, uowServer.respond)
+	defer server.Close()
+
+	uow := newTestClient(server.URL).NewUnitOfWork()
+	account := &SObject{Type: "Account", Fields: map[string]interface{}{"Name": "Acme"}}
+	contact := &SObject{Type: "Contact", Fields: map[string]interface{}{"LastName": "Smith"}}
+	other := &SObject{Type: "Account", Fields: map[string]interface{}{"Name": "Other"}}
+	uow.RegisterNew(contact, account, other)
+	uow.RegisterRelationship(contact, "AccountId", account)
+	uow.RegisterDeleted("001-old")
+	if err := uow.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"create Account", "create Contact", "delete 001-old"}
+	if fmt.Sprint(uowServer.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %q, want %q", uowServer.calls, want)
+	}
+	if account.Id != "Account-1" || other.Id != "Account-2" || contact.Id != "Contact-3" {
+		t.Errorf("ids = %s, %s, %s", account.Id, other.Id, contact.Id)
+	}
+	if contact.Fields["AccountId"] != account.Id {
+		t.Errorf("AccountId = %v, want %s", contact.Fields["AccountId"], account.Id)
+	}
+}
+
+func TestUnitOfWorkRollback(t *testing.T) {
+	tests := []struct {
+		name        string
+		fail        string
+		deleteFails map[string]bool
+		step        string
+		calls       []string
+	}{
+		{
+			name:  "create",
+			fail:  "create Case",
+			step:  "create Case",
+			calls: []string{"create Account", "create Contact", "create Case", "delete Contact-2,Account-1"},
+		},
+		{
+			name:  "update",
+			fail:  "update",
+			step:  "update",
+			calls: []string{"create Account", "create Contact", "create Case", "update", "delete Case-3,Contact-2,Account-1"},
+		},
+		{
+			name:        "delete",
+			deleteFails: map[string]bool{"001-locked": true},
+			step:        "delete",
+			calls: []string{"create Account", "create Contact", "create Case", "update",
+				"delete 001-old,001-locked", "undelete 001-old", "delete Case-3,Contact-2,Account-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uowServer := &unitOfWorkServer{
+				t:           t,
+				fail:        func(call string) bool { return call == tt.fail },
+				deleteFails: tt.deleteFails,
+			}
+			server := newSOAPServer(t, uowServer.respond)
+			defer server.Close()
+
+			uow := newTestClient(server.URL).NewUnitOfWork()
+			account := &SObject{Type: "Account", Fields: map[string]interface{}{"Name": "Acme"}}
+			contact := &SObject{Type: "Contact", Fields: map[string]interface{}{"LastName": "Smith"}}
+			c := &SObject{Type: "Case", Fields: map[string]interface{}{"Subject": "Help"}}
+			uow.RegisterNew(c, contact, account)
+			uow.RegisterRelationship(c, "ContactId", contact)
+			uow.RegisterRelationship(contact, "AccountId", account)
+			uow.RegisterDirty(&SObject{Type: "Account", Id: "001-existing", Fields: map[string]interface{}{"Name": "Renamed"}})
+			uow.RegisterDeleted("001-old", "001-locked")
+
+			err := uow.Commit()
+			var uowErr *UnitOfWorkError
+			if !errors.As(err, &uowErr) || uowErr.Step != tt.step || uowErr.CompensationErr != nil {
+				t.Fatalf("got %v, want a failed %s rolled back", err, tt.step)
+			}
+			if fmt.Sprint(uowServer.calls) != fmt.Sprint(tt.calls) {
+				t.Errorf("calls = %q\nwant    %q", uowServer.calls, tt.calls)
+			}
+			for _, r := range []*SObject{account, contact, c} {
+				if r.Id != "" {
+					t.Errorf("%s still has the id %s of a deleted record", r.Type, r.Id)
+				}
+			}
+		})
+	}
+}